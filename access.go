@@ -0,0 +1,13 @@
+package configoration
+
+// AccessRecorder can be registered with
+// Builder.WithAccessRecorder to observe all
+// value reads of a config, for example to
+// find frequently read or missing keys.
+type AccessRecorder interface {
+	// RecordAccess is called after each read of
+	// key. err is the error returned by the
+	// read, which matches ErrNil if the key does
+	// not exist, or nil on success.
+	RecordAccess(key string, err error)
+}
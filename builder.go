@@ -1,12 +1,24 @@
 package configoration
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path"
-	"sync"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/zekroTJA/configoration/providers"
 )
 
+// stdin buffers os.Stdin for all providers
+// added with AddStdin, as it can only be read
+// once.
+var stdin = providers.NewOnceReader(os.Stdin)
+
 // Builder provides functions to build a config
 // with different source providers.
 //
@@ -15,16 +27,74 @@ import (
 type Builder struct {
 	provider []Provider
 
-	basePath string
+	basePath       string
+	configDirName  string
+	strict         bool
+	strictMerge    bool
+	patchArrays    bool
+	yaml12Booleans bool
+	allowEmpty     bool
+	fileDirectives bool
+	keyOrder       bool
+	err            error
+
+	conflictReporter func(key, fromSource, overriddenSource string)
+	emptyReporter    func(source string)
+	logger           func(event Event)
+	failOnEmpty      bool
+	refreshInterval  time.Duration
+	reloadErrHandler func(err error)
+	reloadHandler    func(changes []Change)
+	keyNormalizer    func(string) string
+	typeErrHandler   func(err error)
+	noArrayIndexing  bool
+	lazyInterpolate  bool
+	overflowPolicy   IntOverflowPolicy
+	readOnly         bool
+	caseFold         bool
+	accessRecorder   AccessRecorder
+	strictTypes      bool
+	rootKey          string
+	schema           *jsonschema.Schema
+	sectionDefaults  []sectionDefaults
+	inlineDefaults   string
+
+	// lastAdded is the index of the provider
+	// added by the last Add call, or -1 if the
+	// call added no provider. It is used by If.
+	lastAdded int
+}
+
+// sectionDefaults describes a defaults section
+// which is applied to all sections within the
+// target section.
+type sectionDefaults struct {
+	defaultsKey string
+	targetKey   string
 }
 
 // NewBuilder returns a new instance of builder.
 func NewBuilder() *Builder {
 	return &Builder{
-		provider: make([]Provider, 0),
+		provider:  make([]Provider, 0),
+		lastAdded: -1,
 	}
 }
 
+// Clone returns a copy of the builder with all
+// added sources and set options. Adding sources
+// to or changing options of the copy does not
+// affect the original builder and vice versa.
+//
+// The added providers themselves are shared
+// between both builders.
+func (b *Builder) Clone() *Builder {
+	bc := *b
+	bc.provider = append([]Provider(nil), b.provider...)
+	bc.sectionDefaults = append([]sectionDefaults(nil), b.sectionDefaults...)
+	return &bc
+}
+
 // SetBasePath sets the base path from which
 // file providers are reading when a relative
 // path is given.
@@ -33,34 +103,809 @@ func (b *Builder) SetBasePath(path string) *Builder {
 	return b
 }
 
+// SetBasePathExpanded sets the base path like
+// SetBasePath after expanding a leading "~" to
+// the home directory of the current user and
+// replacing environment variables like $CONFIG_DIR
+// or ${CONFIG_DIR}. The result is resolved to an
+// absolute path.
+//
+// If mustExist is set and the resulting directory
+// does not exist, Build returns the error.
+func (b *Builder) SetBasePathExpanded(path string, mustExist bool) *Builder {
+	p, err := expandPath(path)
+	if err == nil && mustExist {
+		_, err = os.Stat(p)
+	}
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+
+	return b.SetBasePath(p)
+}
+
+// SetStrictParsing sets whether file providers
+// which are added afterwards check their files
+// for keys which are defined multiple times on
+// the same level. If so, Build returns a
+// *providers.DuplicateKeyError naming the key
+// and the file.
+func (b *Builder) SetStrictParsing(strict bool) *Builder {
+	b.strict = strict
+	return b
+}
+
 // AddJsonFile adds a JSON file provider which
 // reads the passed fileName respecting the set
 // base path. If optional is set, no error is
 // returned when the file does not exist.
 func (b *Builder) AddJsonFile(fileName string, optional bool) *Builder {
-	p := providers.NewJsonProvider(path.Join(b.basePath, fileName), optional)
+	p := providers.NewJsonProvider(path.Join(b.basePath, fileName), optional).
+		SetStrict(b.strict).
+		SetAllowEmpty(b.allowEmpty).
+		SetFileDirectives(b.fileDirectives).
+		SetKeyOrder(b.keyOrder)
+	return b.AddProvider(p)
+}
+
+// AddJsonFileTransform adds a JSON file
+// provider like AddJsonFile whose values are
+// passed to transform before they are merged,
+// for example to rename or drop keys. If
+// transform fails and optional is set, the
+// file is skipped. Otherwise, Build returns
+// the error.
+func (b *Builder) AddJsonFileTransform(fileName string, optional bool, transform TransformFunc) *Builder {
+	p := providers.NewJsonProvider(path.Join(b.basePath, fileName), optional).
+		SetStrict(b.strict).
+		SetAllowEmpty(b.allowEmpty).
+		SetFileDirectives(b.fileDirectives).
+		SetKeyOrder(b.keyOrder)
+	return b.AddProviderTransform(p, transform)
+}
+
+// AddJsonFileE adds a JSON file provider like
+// AddJsonFile but checks the existence of the
+// file immediately. If the file does not exist
+// and optional is not set, the error is returned
+// as *SourceError and no provider is added.
+func (b *Builder) AddJsonFileE(fileName string, optional bool) (*Builder, error) {
+	fullPath := path.Join(b.basePath, fileName)
+	if _, err := os.Stat(fullPath); err != nil {
+		if !os.IsNotExist(err) || !optional {
+			return b, &SourceError{Source: fullPath, Err: err}
+		}
+	}
+	return b.AddJsonFile(fileName, optional), nil
+}
+
+// MustAddJsonFile adds a required JSON file
+// provider like AddJsonFileE and panics if the
+// file does not exist.
+func (b *Builder) MustAddJsonFile(fileName string) *Builder {
+	b, err := b.AddJsonFileE(fileName, false)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// SetConfigDirName sets the name of the application
+// directory which is searched for files in the
+// standard config directories by functions like
+// AddJsonFileFromConfigDir.
+func (b *Builder) SetConfigDirName(name string) *Builder {
+	b.configDirName = name
+	return b
+}
+
+// AddJsonFileFromConfigDir adds a JSON file provider
+// which reads the first file found with the passed
+// name in the set config dir name inside of the
+// standard config directories of the platform.
+//
+// The user config directory (os.UserConfigDir,
+// respecting XDG_CONFIG_HOME on Linux) is searched
+// first. On Linux and BSD systems, the directories
+// in XDG_CONFIG_DIRS (default /etc/xdg) are
+// searched afterwards. If optional is set, no
+// error is returned when the file was not found.
+func (b *Builder) AddJsonFileFromConfigDir(name string, optional bool) *Builder {
+	fileName, err := b.findInConfigDirs(name)
+	if err != nil {
+		if !optional {
+			b.setErr(err)
+		}
+		return b.skipSource()
+	}
+
+	p := providers.NewJsonProvider(fileName, optional).
+		SetStrict(b.strict).
+		SetAllowEmpty(b.allowEmpty).
+		SetFileDirectives(b.fileDirectives).
+		SetKeyOrder(b.keyOrder)
+	return b.AddProvider(p)
+}
+
+// AddJsonFileLazy adds a JSON file provider like
+// AddJsonFile which only decodes the top level of
+// the file on build. Inner objects and arrays are
+// decoded on first access and cached afterwards,
+// which reduces the build time and memory usage
+// for large files of which only few keys are read.
+func (b *Builder) AddJsonFileLazy(fileName string, optional bool) *Builder {
+	p := providers.NewLazyJsonProvider(path.Join(b.basePath, fileName), optional).
+		SetAllowEmpty(b.allowEmpty)
 	return b.AddProvider(p)
 }
 
+// SetAllowEmptyFiles sets whether JSON, YAML and
+// XML file providers which are added afterwards
+// read existing but empty files as an empty
+// config instead of failing to parse them, even
+// if they are not optional. Malformed files
+// still fail to build.
+//
+// INI, properties and CSV files are always read
+// as empty config when they are empty.
+func (b *Builder) SetAllowEmptyFiles(allowEmpty bool) *Builder {
+	b.allowEmpty = allowEmpty
+	return b
+}
+
+// SetFileDirectives sets whether JSON and YAML
+// file providers which are added afterwards
+// replace objects like {"$file": "key.pem"} by
+// the raw content of the referenced file.
+// Relative paths are resolved against the
+// directory of the including file.
+func (b *Builder) SetFileDirectives(enabled bool) *Builder {
+	b.fileDirectives = enabled
+	return b
+}
+
+// SetPreserveKeyOrder sets whether JSON and
+// YAML file providers which are added
+// afterwards record the order of the keys in
+// their files, so that Config.Export writes
+// the keys of each section in the order they
+// are defined in the sources instead of sorted.
+// Keys added by later sources follow the keys
+// of earlier sources.
+func (b *Builder) SetPreserveKeyOrder(enabled bool) *Builder {
+	b.keyOrder = enabled
+	return b
+}
+
+// SetYaml12Booleans sets whether YAML providers
+// which are added afterwards only decode the
+// YAML 1.2 literals true and false as booleans.
+// If set, YAML 1.1 literals like yes, no, on and
+// off are kept as strings and can still be read
+// with GetString as written in the file.
+func (b *Builder) SetYaml12Booleans(enabled bool) *Builder {
+	b.yaml12Booleans = enabled
+	return b
+}
+
 // AddYamlFile adds a YAML file provider which
 // reads the passed fileName respecting the set
 // base path. If optional is set, no error is
 // returned when the file does not exist.
 func (b *Builder) AddYamlFile(fileName string, optional bool) *Builder {
-	p := providers.NewYamlProvider(path.Join(b.basePath, fileName), optional)
+	p := providers.NewYamlProvider(path.Join(b.basePath, fileName), optional).
+		SetStrict(b.strict).
+		SetYaml12Booleans(b.yaml12Booleans).
+		SetAllowEmpty(b.allowEmpty).
+		SetFileDirectives(b.fileDirectives).
+		SetKeyOrder(b.keyOrder)
+	return b.AddProvider(p)
+}
+
+// AddEnvironmentVariables adds an environment
+// variable provider which reads all variables
+// starting with the passed prefix. The prefix is
+// trimmed from the keys and "__" separates
+// sections, while single underscores are kept,
+// so DB__CONNECTION_STRING is read as
+// "DB:CONNECTION_STRING". The separator can be
+// changed with providers.WithNestingSeparator.
+// If lowercase is set, keys are converted to
+// lower case.
+//
+// Optional behavior, like reading secrets from
+// files, can be enabled by passing
+// providers.EnvOption values.
+func (b *Builder) AddEnvironmentVariables(prefix string, lowercase bool, opts ...providers.EnvOption) *Builder {
+	p := providers.NewEnvProvider(prefix, lowercase, opts...)
 	return b.AddProvider(p)
 }
 
-// TODO: Docs
-func (b *Builder) AddEnvironmentVariables(prefix string, lowercase bool) *Builder {
-	p := providers.NewEnvProvider(prefix, lowercase)
+// AddIniFile adds an INI file provider which
+// reads the passed fileName respecting the set
+// base path. Each [section] becomes a section
+// and entries outside of any section are set
+// on the root level. If optional is set, no
+// error is returned when the file does not
+// exist.
+func (b *Builder) AddIniFile(fileName string, optional bool) *Builder {
+	p := providers.NewIniProvider(path.Join(b.basePath, fileName), optional)
+	return b.AddProvider(p)
+}
+
+// AddPropertiesFile adds a Java .properties file
+// provider which reads the passed fileName
+// respecting the set base path. Dotted keys like
+// "db.pool.size" are expanded into nested
+// sections. If optional is set, no error is
+// returned when the file does not exist.
+func (b *Builder) AddPropertiesFile(fileName string, optional bool) *Builder {
+	p := providers.NewPropertiesProvider(path.Join(b.basePath, fileName), optional)
+	return b.AddProvider(p)
+}
+
+// AddXmlFile adds a XML file provider which
+// reads the passed fileName respecting the set
+// base path. See providers.NewXmlProvider for
+// the mapping of elements and attributes. If
+// optional is set, no error is returned when
+// the file does not exist.
+func (b *Builder) AddXmlFile(fileName string, optional bool) *Builder {
+	p := providers.NewXmlProvider(path.Join(b.basePath, fileName), optional).
+		SetAllowEmpty(b.allowEmpty)
+	return b.AddProvider(p)
+}
+
+// AddTomlFile adds a TOML file provider which
+// reads the passed fileName respecting the set
+// base path. If optional is set, no error is
+// returned when the file does not exist.
+func (b *Builder) AddTomlFile(fileName string, optional bool) *Builder {
+	p := providers.NewTomlProvider(path.Join(b.basePath, fileName), optional)
+	return b.AddProvider(p)
+}
+
+// AddDotEnvFile adds a .env file provider
+// which reads KEY=value pairs from the passed
+// file relative to the base path. Keys are
+// split by "__" into nested sections. If
+// optional is set, no error is returned when
+// the file does not exist.
+func (b *Builder) AddDotEnvFile(fileName string, optional bool) *Builder {
+	p := providers.NewDotEnvProvider(path.Join(b.basePath, fileName), optional)
+	return b.AddProvider(p)
+}
+
+// AddDotEnvFiles adds a .env file provider for
+// each of the passed files in order, so that
+// later files override the values of earlier
+// ones, like .env, .env.local and .env.prod.
+// If optional is set, no error is returned
+// when a file does not exist.
+func (b *Builder) AddDotEnvFiles(fileNames []string, optional bool) *Builder {
+	b.skipSource()
+	for _, fileName := range fileNames {
+		b.AddDotEnvFile(fileName, optional)
+	}
+	return b
+}
+
+// AddFile adds a file provider for the passed
+// file relative to the base path which is
+// selected by the extension of the file. The
+// extensions .json, .yaml, .yml, .toml, .xml,
+// .ini, .properties and .env are supported.
+//
+// If the extension is not supported and
+// optional is not set, Build returns an error
+// matching ErrUnsupportedFormat.
+func (b *Builder) AddFile(fileName string, optional bool) *Builder {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".json":
+		return b.AddJsonFile(fileName, optional)
+	case ".yaml", ".yml":
+		return b.AddYamlFile(fileName, optional)
+	case ".toml":
+		return b.AddTomlFile(fileName, optional)
+	case ".xml":
+		return b.AddXmlFile(fileName, optional)
+	case ".ini":
+		return b.AddIniFile(fileName, optional)
+	case ".properties":
+		return b.AddPropertiesFile(fileName, optional)
+	case ".env":
+		return b.AddDotEnvFile(fileName, optional)
+	}
+
+	if !optional {
+		b.setErr(fmt.Errorf("%s: %w", fileName, ErrUnsupportedFormat))
+	}
+	return b.skipSource()
+}
+
+// AddRaw adds a provider which parses data in
+// the passed format, which is one of json,
+// yaml (or yml), toml and env. If optional is
+// set, empty data is read as empty config. Data
+// which can not be parsed always fails the
+// build.
+//
+// If the format is not supported, Build returns
+// an error matching ErrUnsupportedFormat, even
+// if optional is set.
+func (b *Builder) AddRaw(format string, data string, optional bool) *Builder {
+	format, ok := b.rawFormat(format)
+	if !ok {
+		return b.skipSource()
+	}
+
+	p := providers.NewRawProvider(format, []byte(data), optional).
+		SetYaml12Booleans(b.yaml12Booleans)
+	return b.AddProvider(p)
+}
+
+// AddStdin adds a provider which parses the
+// data piped to stdin in the passed format,
+// like AddRaw. Stdin is read once on the first
+// build and the read data is shared by all
+// configs and reused on reloads. If optional is
+// not set, Build returns an error matching
+// providers.ErrEmptyStdin if stdin is empty or
+// only contains whitespace.
+func (b *Builder) AddStdin(format string, optional bool) *Builder {
+	format, ok := b.rawFormat(format)
+	if !ok {
+		return b.skipSource()
+	}
+
+	p := providers.NewStdinProvider(format, stdin, optional).
+		SetYaml12Booleans(b.yaml12Booleans)
+	return b.AddProvider(p)
+}
+
+// rawFormat returns the normalized name of the
+// passed format of raw data. If the format is
+// not supported, ok is false and an error
+// matching ErrUnsupportedFormat is recorded.
+func (b *Builder) rawFormat(format string) (string, bool) {
+	format = strings.ToLower(format)
+	switch format {
+	case "json", "toml", "env":
+	case "yaml", "yml":
+		format = "yaml"
+	default:
+		b.setErr(fmt.Errorf("%s: %w", format, ErrUnsupportedFormat))
+		return "", false
+	}
+	return format, true
+}
+
+// AddCsvFile adds a CSV file provider which
+// reads the passed fileName respecting the set
+// base path. The rows of the file are set as a
+// slice of sections keyed by the header row
+// under the passed key, so that values can be
+// read with keys like "regions:0:code". If
+// optional is set, no error is returned when
+// the file does not exist.
+func (b *Builder) AddCsvFile(fileName, key string, optional bool) *Builder {
+	p := providers.NewCsvProvider(path.Join(b.basePath, fileName), key, optional)
+	return b.AddProvider(p)
+}
+
+// AddScalarFile adds a provider which reads the
+// content of the passed fileName, respecting the
+// set base path, as single string value of key.
+// This is useful for files containing a bare
+// value like mounted secrets. If optional is
+// set, no error is returned when the file does
+// not exist.
+//
+// Use providers.NewScalarFileProvider with
+// SetInferTypes to parse the content to numbers
+// and bools.
+func (b *Builder) AddScalarFile(key, fileName string, optional bool) *Builder {
+	p := providers.NewScalarFileProvider(path.Join(b.basePath, fileName), key, optional)
+	return b.AddProvider(p)
+}
+
+// AddStruct adds a struct provider which maps
+// the fields of the passed struct (or pointer to
+// a struct) v to config values. Fields are
+// named by their `config:"name"` tag or by their
+// field name and nested structs become nested
+// sections. If optional is set, no error is
+// returned when v is nil.
+func (b *Builder) AddStruct(v interface{}, optional bool) *Builder {
+	p := providers.NewStructProvider(v, optional)
+	return b.AddProvider(p)
+}
+
+// AddMap adds the passed map as config
+// source. Nested maps become sections, so a
+// ConfigMap built via NewConfigMap and Set can
+// be passed directly.
+func (b *Builder) AddMap(m map[string]interface{}) *Builder {
+	p := providers.NewMapProvider(m)
+	return b.AddProvider(p)
+}
+
+// AddRedisHash adds a Redis hash provider which
+// reads all fields of the hash at key using the
+// passed client. Fields containing ":" are
+// expanded into nested sections and all values
+// are strings. If optional is set, no error is
+// returned when the hash could not be read.
+func (b *Builder) AddRedisHash(client providers.RedisHashClient, key string, optional bool) *Builder {
+	p := providers.NewRedisHashProvider(client, key, optional)
+	return b.AddProvider(p)
+}
+
+// AddSSM adds an AWS SSM Parameter Store
+// provider which recursively reads all
+// parameters below path using the passed
+// client. The "/" delimited parameter names
+// are expanded into nested sections. If
+// decrypt is set, SecureString values are
+// decrypted. If optional is set, no error is
+// returned when the parameters could not be
+// read.
+func (b *Builder) AddSSM(client providers.SSMClient, path string, optional, decrypt bool) *Builder {
+	p := providers.NewSSMProvider(client, path, optional, decrypt)
 	return b.AddProvider(p)
 }
 
 // AddProvider adds a generic Provider instance
 // which must implememt the Provider interface.
+//
+// If p implements io.Closer, it is owned by the
+// built config and closed by Config.Close, so
+// it must not be shared with other configs.
 func (b *Builder) AddProvider(p Provider) *Builder {
 	b.provider = append(b.provider, p)
+	b.lastAdded = len(b.provider) - 1
+	return b
+}
+
+// skipSource records that the current Add call
+// added no provider, so that a following If is
+// a no-op.
+func (b *Builder) skipSource() *Builder {
+	b.lastAdded = -1
+	return b
+}
+
+// If makes the most recently added source
+// conditional, so that it is only read if cond
+// returns true. cond is evaluated on every
+// build and reload, and excluded sources
+// provide no values. For example,
+//
+//	b.AddJsonFile("remote.json", false).If(isProduction)
+//
+// only reads remote.json if isProduction
+// returns true. If the previous call added no
+// source, like AddFile for an optional file of
+// an unsupported format, If is a no-op. For
+// calls adding multiple sources, like
+// AddDotEnvFiles, only the last one is made
+// conditional.
+func (b *Builder) If(cond func() bool) *Builder {
+	last := b.lastAdded
+	if last < 0 || last >= len(b.provider) {
+		return b
+	}
+	b.provider[last] = &conditionalProvider{Provider: b.provider[last], cond: cond}
+	return b
+}
+
+// AddProviderTransform adds p like AddProvider
+// but passes its values to transform before
+// they are merged. If transform fails and p is
+// an OptionalProvider reporting to be
+// optional, the source is skipped. Otherwise,
+// Build returns the error.
+func (b *Builder) AddProviderTransform(p Provider, transform TransformFunc) *Builder {
+	return b.AddProvider(&transformProvider{Provider: p, transform: transform})
+}
+
+// SetStrictMerge sets whether Build fails when a
+// source defines a key as value which a previous
+// source defined as section or vice versa. The
+// returned error matches ErrTypeConflict.
+//
+// If strict merge is disabled, the later source
+// replaces the key and the conflict is reported
+// to the conflict reporter, if registered.
+func (b *Builder) SetStrictMerge(strict bool) *Builder {
+	b.strictMerge = strict
+	return b
+}
+
+// SetArrayPatching sets whether sources can
+// override single elements of arrays defined
+// by previous sources instead of replacing the
+// whole array.
+//
+// If enabled, an object whose keys are all
+// indices patches the elements of the array
+// at the same key. For example, the override
+// {"listeners": {"1": {"port": 8443}}} only
+// changes the port of the second element of
+// [{"port": 80}, {"port": 443}] and keeps the
+// first element. The same works with
+// environment variables like
+// LISTENERS__1__PORT=8443. Objects are merged
+// into the elements and other values replace
+// them. Indices beyond the end of the array
+// append elements and gaps are filled with
+// null. Without a previous array, the object
+// is merged as section.
+func (b *Builder) SetArrayPatching(enabled bool) *Builder {
+	b.patchArrays = enabled
+	return b
+}
+
+// WithConflictReporter registers a function
+// which is called during Build whenever a key
+// of a source overwrites a value which was
+// already set by a previously added source.
+//
+// key is the full path of the overwritten key,
+// fromSource the name of the overwriting source
+// and overriddenSource the name of the source
+// which set the value before.
+func (b *Builder) WithConflictReporter(fn func(key, fromSource, overriddenSource string)) *Builder {
+	b.conflictReporter = fn
+	return b
+}
+
+// WithLogger sets a function which is called
+// with an Event for each source read, each
+// overridden key and each build or reload of
+// the config, for example to log slow sources.
+func (b *Builder) WithLogger(fn func(event Event)) *Builder {
+	b.logger = fn
+	return b
+}
+
+// WarnOnEmptySources sets a function which is
+// called on build with the name of each file
+// source which is not optional but provided no
+// values, like a mis-pathed or empty config
+// file. Other sources, like environment
+// variables, are not reported.
+func (b *Builder) WarnOnEmptySources(fn func(source string)) *Builder {
+	b.emptyReporter = fn
+	return b
+}
+
+// SetFailOnEmptySources sets whether the build
+// fails with ErrEmptySource when a file source
+// which is not optional provided no values.
+func (b *Builder) SetFailOnEmptySources(fail bool) *Builder {
+	b.failOnEmpty = fail
+	return b
+}
+
+// WithKeyNormalizer registers a function which is
+// applied to every key of all sources on build
+// and to every section of keys passed to the
+// getters of the built config so that both stay
+// consistent.
+//
+// If two keys of the same section of a source
+// result in the same normalized key, the conflict
+// reporter, if registered, is called with the
+// normalized key and the name of the source as
+// both sources. The value of the key which is
+// already normalized is kept. Otherwise, the
+// value of the lexically greatest key is kept.
+func (b *Builder) WithKeyNormalizer(fn func(string) string) *Builder {
+	b.keyNormalizer = fn
+	return b
+}
+
+// WithInvalidTypeHandler registers a function
+// which is called by the *OrDef getters of the
+// built config when a value exists but can not
+// be converted to the requested type.
+//
+// The getters still return the default value in
+// this case, but err, which matches
+// ErrInvalidType, is passed to fn first.
+// Missing keys do not call fn.
+func (b *Builder) WithInvalidTypeHandler(fn func(err error)) *Builder {
+	b.typeErrHandler = fn
+	return b
+}
+
+// DisableArrayIndexing disables the selection of
+// slice elements by their index in keys like
+// "servers:0:host" for the built config. Numeric
+// sections then only select map keys, and
+// slices can only be read as a whole.
+func (b *Builder) DisableArrayIndexing() *Builder {
+	b.noArrayIndexing = true
+	return b
+}
+
+// LazyInterpolation enables resolving ${VAR}
+// tokens in string values against the current
+// environment on every GetString call of the
+// built config instead of once, so changes of
+// the environment are picked up on the next
+// read. Unset variables resolve to an empty
+// string.
+//
+// As the tokens are resolved on every read,
+// GetString becomes slower by the time needed
+// to scan the value for tokens.
+func (b *Builder) LazyInterpolation() *Builder {
+	b.lazyInterpolate = true
+	return b
+}
+
+// WithCaseFoldFallback enables a fallback for
+// lookups of keys which do not exist with the
+// exact case, which are retried case
+// insensitively, so that GetString("mykey")
+// reads "MyKey". Exact matches always take
+// precedence.
+func (b *Builder) WithCaseFoldFallback() *Builder {
+	b.caseFold = true
+	return b
+}
+
+// WithAccessRecorder registers rec, which is
+// called on every value read of the built
+// config, like by GetValue or GetString, with
+// the full path of the requested key and the
+// outcome of the read. Reads of sub sections
+// are recorded with the path of the section,
+// like "db:host" for GetSection("db") and
+// "host". rec must be safe for concurrent use.
+func (b *Builder) WithAccessRecorder(rec AccessRecorder) *Builder {
+	b.accessRecorder = rec
+	return b
+}
+
+// StrictTypes disables the conversion of values
+// in the scalar getters, so that GetInt returns
+// an error matching ErrInvalidType for the
+// string "5" instead of parsing it. Floats are
+// still accepted by the int getters if they are
+// integral, as JSON numbers are decoded as
+// float64, and ints by the float getters.
+func (b *Builder) StrictTypes() *Builder {
+	b.strictTypes = true
+	return b
+}
+
+// ReadOnly builds an immutable config whose
+// getters do not take any locks, which speeds
+// up concurrent reads of configs which are
+// built once and never changed.
+//
+// All sections are resolved on build. Reloads
+// and mutating functions like GetOrSet return
+// ErrReadOnly, and Build fails with ErrReadOnly
+// if a refresh interval is set as well as
+// BuildAndWatch.
+func (b *Builder) ReadOnly() *Builder {
+	b.readOnly = true
+	return b
+}
+
+// WithIntOverflowPolicy sets how the sized
+// integer getters like GetInt32 of the built
+// config handle values exceeding the range of
+// the requested type. Defaults to
+// OverflowError.
+func (b *Builder) WithIntOverflowPolicy(policy IntOverflowPolicy) *Builder {
+	b.overflowPolicy = policy
+	return b
+}
+
+// ValidateAgainstSchema validates the merged
+// config values as JSON against the passed JSON
+// Schema on build and reload. If the values
+// violate the schema, an error joining a
+// *ValidationError for each violation is
+// returned, which matches ErrValidationFailed.
+//
+// If the schema is invalid, Build returns the
+// compilation error.
+func (b *Builder) ValidateAgainstSchema(schema []byte) *Builder {
+	s, err := compileSchema(schema)
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+	b.schema = s
+	return b
+}
+
+// SetRootKey sets the key of a section which
+// becomes the root of the built config after
+// all sources are merged, so that
+// GetString("port") reads "app:port" for the
+// root key "app". If the key does not select a
+// section, Build returns an error matching
+// ErrNil.
+func (b *Builder) SetRootKey(key string) *Builder {
+	b.rootKey = key
+	return b
+}
+
+// WithSectionDefaults registers a section whose
+// values are applied on build to each section
+// within the section of targetKey, unless they
+// are set in the target sections. Inner sections
+// are applied recursively.
+//
+// For example, WithSectionDefaults("defaults",
+// "workers") applies the values of "defaults" to
+// "workers:a", "workers:b" and so on. Values
+// within targetKey which are no sections are
+// left as is. Multiple defaults sections can be
+// registered and are applied in order.
+func (b *Builder) WithSectionDefaults(defaultsKey, targetKey string) *Builder {
+	b.sectionDefaults = append(b.sectionDefaults, sectionDefaults{
+		defaultsKey: defaultsKey,
+		targetKey:   targetKey,
+	})
+	return b
+}
+
+// WithInlineDefaultsSection sets the key of a
+// section within the config which is looked up
+// by the getters for keys missing in the
+// config, like "_defaults:db:port" for
+// "db:port". Unlike defaults passed to
+// Section.WithDefaults, they are kept in the
+// config files themselves. The defaults are
+// only used on reads, so they do not show up
+// in functions like Keys or Export. The key is
+// relative to the root key, if set.
+func (b *Builder) WithInlineDefaultsSection(name string) *Builder {
+	b.inlineDefaults = name
+	return b
+}
+
+// WithRefreshInterval enables the periodic
+// refresh of the built Config. Every interval,
+// all sources are fetched again and the merged
+// result replaces the current config values.
+//
+// If a refresh fails, the last successfully
+// built values are kept. The refresh is stopped
+// by calling Close on the built Config.
+func (b *Builder) WithRefreshInterval(interval time.Duration) *Builder {
+	b.refreshInterval = interval
+	return b
+}
+
+// WithReloadErrorHandler registers a function
+// which is called with the error of every failed
+// reload of the built config, either by the
+// periodic refresh or by BuildAndWatch.
+func (b *Builder) WithReloadErrorHandler(fn func(err error)) *Builder {
+	b.reloadErrHandler = fn
+	return b
+}
+
+// OnReload registers a function which is called
+// once after every reload of the built config
+// which changed its values. changes contains
+// all added, removed and modified values
+// sorted by key.
+//
+// fn is called after the new values are
+// applied, so the config can be read within fn.
+func (b *Builder) OnReload(fn func(changes []Change)) *Builder {
+	b.reloadHandler = fn
 	return b
 }
 
@@ -69,20 +914,326 @@ func (b *Builder) AddProvider(p Provider) *Builder {
 // config, which is returned.
 //
 // If a registered provider fails, the build
-// stops and returns the error. The resulting
-// Section will be nil.
-func (b *Builder) Build() (Section, error) {
-	res := make(ConfigMap)
-	for _, prov := range b.provider {
-		m, err := prov.GetMap()
+// stops and returns the error wrapped into a
+// *SourceError. The resulting Config will
+// be nil.
+func (b *Builder) Build() (Config, error) {
+	if b.readOnly && b.refreshInterval > 0 {
+		return nil, ErrReadOnly
+	}
+
+	c, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+
+	if b.refreshInterval > 0 {
+		c.startRefresh(b.refreshInterval)
+	}
+
+	return c, nil
+}
+
+// BuildAndWatch builds the config like Build and
+// starts watching all sources for changes. The
+// sources are reloaded every refresh interval set
+// with WithRefreshInterval or every
+// DefaultWatchInterval if none is set.
+//
+// Each time a reload changes the config values,
+// a notification is sent on the returned channel.
+// Notifications are not queued, so a slow
+// receiver only gets one notification for
+// multiple changes. The channel is closed when
+// ctx is done or Close is called on the config.
+//
+// Errors of the initial build are returned
+// directly. Reload errors are passed to the
+// handler set with WithReloadErrorHandler and
+// the last valid values are kept.
+func (b *Builder) BuildAndWatch(ctx context.Context) (Config, <-chan struct{}, error) {
+	if b.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+
+	c, err := b.build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	interval := b.refreshInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	return c, c.startWatch(ctx, interval), nil
+}
+
+// build executes all providers and returns the
+// resulting config without starting any
+// background tasks.
+func (b *Builder) build() (*config, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	start := time.Now()
+	states, err := b.loadSources(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.mergeSources(states)
+	if err != nil {
+		return nil, err
+	}
+	b.emit(Event{Type: EventBuilt, Duration: time.Since(start)})
+
+	c := newConfig(b.Clone(), res)
+	c.states = states
+	c.order = b.mergeKeyOrder(states)
+	return c, nil
+}
+
+// sourceState holds the values read from a
+// source and, for file providers, the state of
+// the file at that time, so that unchanged
+// sources do not have to be read again.
+type sourceState struct {
+	m       map[string]interface{}
+	order   providers.KeyOrder
+	modTime time.Time
+	size    int64
+}
+
+// changedSince returns true if the file state of
+// st differs from prev or is unknown.
+func (st sourceState) changedSince(prev sourceState) bool {
+	return st.modTime.IsZero() || !st.modTime.Equal(prev.modTime) || st.size != prev.size
+}
+
+// loadSources reads the values of all providers.
+// If prev is set, the values of prev are reused
+// for all providers for which reread returns
+// false. reread is passed the index of the
+// provider and its current file state.
+func (b *Builder) loadSources(prev []sourceState,
+	reread func(i int, st sourceState) bool) ([]sourceState, error) {
+
+	states := make([]sourceState, len(b.provider))
+	for i, prov := range b.provider {
+		var st sourceState
+		if fp, ok := prov.(FileProvider); ok && fp.FileName() != "" {
+			if info, err := os.Stat(fp.FileName()); err == nil {
+				st.modTime, st.size = info.ModTime(), info.Size()
+			}
+		}
+
+		if prev != nil && !reread(i, st) {
+			states[i] = prev[i]
+			continue
+		}
+
+		m, err := b.readSource(prov)
 		if err != nil {
 			return nil, err
 		}
-		res.merge(m)
+		st.m = m
+		if op, ok := unwrapProvider(prov).(OrderedProvider); ok {
+			st.order = b.normalizeKeyOrder(op.KeyOrder())
+		}
+		states[i] = st
+	}
+
+	return states, nil
+}
+
+// readSource reads the values of prov and
+// normalizes their keys.
+func (b *Builder) readSource(prov Provider) (map[string]interface{}, error) {
+	name := providerName(prov)
+	start := time.Now()
+	m, err := prov.GetMap()
+	if err != nil {
+		b.emit(Event{Type: EventSourceFailed, Source: name, Duration: time.Since(start), Err: err})
+		return nil, &SourceError{Source: name, Err: err}
+	}
+	b.emit(Event{Type: EventSourceLoaded, Source: name, Duration: time.Since(start)})
+
+	if len(m) == 0 && !isOptional(prov) && isFileSource(prov) {
+		if b.emptyReporter != nil {
+			b.emptyReporter(name)
+		}
+		if b.failOnEmpty {
+			return nil, &SourceError{Source: name, Err: ErrEmptySource}
+		}
+	}
+
+	if b.keyNormalizer != nil && m != nil {
+		var onCollision func(key string)
+		if b.conflictReporter != nil {
+			onCollision = func(key string) {
+				b.conflictReporter(key, name, name)
+			}
+		}
+		m = normalizeKeys(m, b.keyNormalizer, "", onCollision)
+	}
+
+	return m, nil
+}
+
+// mergeSources merges the values of all states
+// in the order of the providers, applies the
+// section defaults and selects the root key.
+func (b *Builder) mergeSources(states []sourceState) (ConfigMap, error) {
+	res := make(ConfigMap)
+	origins := make(map[string]string)
+	for i, st := range states {
+		name := providerName(b.provider[i])
+
+		var onSet mergeFunc
+		if b.conflictReporter != nil || b.strictMerge || b.logger != nil {
+			onSet = func(key string, exists, typeChanged bool) error {
+				if typeChanged && b.strictMerge {
+					return newKeyError(key, ErrTypeConflict)
+				}
+				if exists && b.conflictReporter != nil {
+					b.conflictReporter(key, name, origins[key])
+				}
+				if exists {
+					b.emit(Event{Type: EventKeyOverridden, Source: name, Key: key, OverriddenSource: origins[key]})
+				}
+				origins[key] = name
+				return nil
+			}
+		}
+		if err := res.mergeWith(st.m, "", onSet, b.patchArrays); err != nil {
+			return nil, &SourceError{Source: name, Err: err}
+		}
+	}
+
+	for _, sd := range b.sectionDefaults {
+		b.applySectionDefaults(res, sd)
+	}
+
+	if b.rootKey != "" {
+		root, ok := res.sectionAt(b.normalizePath(b.rootKey))
+		if !ok {
+			return nil, newKeyError(b.rootKey, ErrNil)
+		}
+		res = root
+	}
+
+	if b.schema != nil {
+		if err := validateSchema(b.schema, res); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// applySectionDefaults applies the defaults
+// section of sd to all sections within the
+// target section of sd in m.
+func (b *Builder) applySectionDefaults(m ConfigMap, sd sectionDefaults) {
+	defaultsPath := b.normalizePath(sd.defaultsKey)
+	defaults, ok := m.sectionAt(defaultsPath)
+	if !ok {
+		return
+	}
+
+	targetPath := b.normalizePath(sd.targetKey)
+	target, ok := m.sectionAt(targetPath)
+	if !ok {
+		return
+	}
+
+	targetKey := strings.Join(targetPath, Delimiter)
+	defaultsKey := strings.Join(defaultsPath, Delimiter)
+	for k := range target {
+		if joinPath(targetKey, k) == defaultsKey {
+			continue
+		}
+		if sec, ok := target.sectionAt([]string{k}); ok {
+			sec.fillDefaults(defaults)
+		}
+	}
+}
+
+// normalizePath splits key into its sections
+// and applies the key normalizer, if set.
+func (b *Builder) normalizePath(key string) []string {
+	segments := splitSections(key)
+	if b.keyNormalizer != nil {
+		for i, seg := range segments {
+			segments[i] = b.keyNormalizer(seg)
+		}
+	}
+	return segments
+}
+
+// setErr sets err as the error returned by
+// Build if no other error was set before.
+func (b *Builder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// expandPath expands a leading "~" in p to the
+// home directory of the current user, replaces
+// environment variables and returns the absolute
+// representation of the resulting path.
+func expandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(home, p[1:])
+	}
+
+	return filepath.Abs(os.ExpandEnv(p))
+}
+
+// configDirs returns the standard config
+// directories of the platform ordered by
+// precedence.
+func configDirs() ([]string, error) {
+	userDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{userDir}
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" && runtime.GOOS != "plan9" {
+		xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+		if xdgDirs == "" {
+			xdgDirs = "/etc/xdg"
+		}
+		dirs = append(dirs, filepath.SplitList(xdgDirs)...)
+	}
+
+	return dirs, nil
+}
+
+// findInConfigDirs returns the path of the first
+// existing file with the passed name in the config
+// dir name inside of the standard config
+// directories. If none exists, the path in the
+// user config directory is returned.
+func (b *Builder) findInConfigDirs(name string) (string, error) {
+	dirs, err := configDirs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range dirs {
+		fileName := filepath.Join(dir, b.configDirName, name)
+		if _, err := os.Stat(fileName); err == nil {
+			return fileName, nil
+		}
 	}
 
-	return &section{
-		mtx: sync.Mutex{},
-		m:   res,
-	}, nil
+	return filepath.Join(dirs[0], b.configDirName, name), nil
 }
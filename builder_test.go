@@ -1,8 +1,19 @@
 package configoration
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/zekroTJA/configoration/providers"
 )
@@ -31,6 +42,482 @@ func TestSetBasePath(t *testing.T) {
 	}
 }
 
+func TestSetBasePathExpanded(t *testing.T) {
+	home, err := ioutil.TempDir("", "configoration")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	os.Setenv("CONFIG_DIR", filepath.Join(home, "config"))
+	defer os.Unsetenv("CONFIG_DIR")
+
+	{
+		b := NewBuilder().
+			SetBasePathExpanded("~/config", false)
+		assert(t, b.basePath, filepath.Join(home, "config"))
+	}
+	{
+		b := NewBuilder().
+			SetBasePathExpanded("$CONFIG_DIR/app", false)
+		assert(t, b.basePath, filepath.Join(home, "config", "app"))
+	}
+	{
+		b := NewBuilder().
+			SetBasePathExpanded("testdata", false)
+		wd, _ := os.Getwd()
+		assert(t, b.basePath, filepath.Join(wd, "testdata"))
+	}
+	{
+		_, err := NewBuilder().
+			SetBasePathExpanded("~/config", true).
+			Build()
+		if !os.IsNotExist(err) {
+			t.Errorf("build with non-existent base path did not fail: %v", err)
+		}
+	}
+	{
+		_, err := NewBuilder().
+			SetBasePathExpanded("~", true).
+			Build()
+		if err != nil {
+			t.Errorf("build with existent base path failed: %s", err.Error())
+		}
+	}
+}
+
+func TestWithCaseFoldFallback(t *testing.T) {
+	m := NewConfigMap().
+		Set("MyKey", "value").
+		Set("mykey2", "exact").
+		Set("MYKEY2", "folded").
+		Set("Server:Port", 8080)
+
+	c, err := NewBuilder().AddMap(m).Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	if _, err = c.GetString("mykey"); !errors.Is(err, ErrNil) {
+		t.Errorf("lookup without fallback did not return ErrNil: %v", err)
+	}
+
+	for _, b := range []*Builder{
+		NewBuilder().AddMap(m).WithCaseFoldFallback(),
+		NewBuilder().AddMap(m).WithCaseFoldFallback().ReadOnly(),
+	} {
+		c, err := b.Build()
+		if err != nil {
+			t.Fatalf("build failed: %s", err.Error())
+		}
+		{
+			v, err := c.GetString("mykey")
+			assertVal(t, v, err, "value")
+		}
+		{
+			v, err := c.GetString("mykey2")
+			assertVal(t, v, err, "exact")
+		}
+		{
+			v, err := c.GetInt("server:port")
+			assertVal(t, v, err, 8080)
+		}
+		{
+			_, err := c.GetString("otherkey")
+			if !errors.Is(err, ErrNil) {
+				t.Errorf("missing key did not return ErrNil: %v", err)
+			}
+		}
+	}
+
+	// keys added at runtime are found case
+	// insensitively as well
+	c, err = NewBuilder().AddMap(m).WithCaseFoldFallback().Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	if _, err = c.GetString("otherkey"); !errors.Is(err, ErrNil) {
+		t.Errorf("missing key did not return ErrNil: %v", err)
+	}
+	compute := func() (interface{}, error) { return "computed", nil }
+	for _, key := range []string{"OtherKey", "Server:Host"} {
+		if _, err = c.GetOrSet(key, compute); err != nil {
+			t.Fatalf("get or set of %q failed: %s", key, err.Error())
+		}
+	}
+	for _, key := range []string{"otherkey", "server:host", "SERVER:HOST"} {
+		v, err := c.GetString(key)
+		assertVal(t, v, err, "computed")
+	}
+}
+
+type accessRecord struct {
+	key    string
+	missed bool
+}
+
+type mockAccessRecorder struct {
+	records []accessRecord
+}
+
+func (r *mockAccessRecorder) RecordAccess(key string, err error) {
+	r.records = append(r.records, accessRecord{key, errors.Is(err, ErrNil)})
+}
+
+func TestWithAccessRecorder(t *testing.T) {
+	rec := &mockAccessRecorder{}
+	c, err := NewBuilder().
+		AddMap(NewConfigMap().Set("db:host", "localhost").Set("db:port", 5432)).
+		WithAccessRecorder(rec).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	c.GetString("db:host")
+	c.GetInt("db:port")
+	c.GetString("db:user")
+	c.GetSection("db").GetString("host")
+	c.WithPrefix("db").GetValue("password")
+	c.GetSection("db").WithPrefix("tls").GetValue("cert")
+
+	if !reflect.DeepEqual(rec.records, []accessRecord{
+		{"db:host", false},
+		{"db:port", false},
+		{"db:user", true},
+		{"db:host", false},
+		{"db:password", true},
+		{"db:tls:cert", true},
+	}) {
+		t.Errorf("records (%+v) were not like expected", rec.records)
+	}
+}
+
+func TestSetArrayPatching(t *testing.T) {
+	base := map[string]interface{}{
+		"listeners": []interface{}{
+			map[string]interface{}{"host": "a", "port": 80},
+			map[string]interface{}{"host": "b", "port": 443},
+		},
+		"tags": []interface{}{"one", "two"},
+	}
+	override := map[string]interface{}{
+		"listeners": map[string]interface{}{
+			"1": map[string]interface{}{"port": 8443},
+		},
+		"tags": map[string]interface{}{
+			"0": "first",
+			"2": "three",
+		},
+	}
+
+	c, err := NewBuilder().
+		SetArrayPatching(true).
+		AddMap(base).
+		AddMap(override).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("listeners:0:port")
+		assertVal(t, v, err, 80)
+	}
+	{
+		v, err := c.GetString("listeners:1:host")
+		assertVal(t, v, err, "b")
+	}
+	{
+		v, err := c.GetInt("listeners:1:port")
+		assertVal(t, v, err, 8443)
+	}
+	{
+		v, err := c.GetStringSlice("tags")
+		assertSlice(t, v, err, []string{"first", "two", "three"})
+	}
+	if base["listeners"].([]interface{})[1].(map[string]interface{})["port"] != 443 {
+		t.Error("source array was modified")
+	}
+
+	c, err = NewBuilder().
+		AddMap(base).
+		AddMap(override).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		_, err := c.GetValue("listeners:0")
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("array was patched without array patching: %v", err)
+		}
+	}
+}
+
+func TestSetRootKey(t *testing.T) {
+	m := NewConfigMap().
+		Set("app:port", 8080).
+		Set("app:db:host", "localhost").
+		Set("other", "value")
+
+	c, err := NewBuilder().AddMap(m).SetRootKey("app").Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("port")
+		assertVal(t, v, err, 8080)
+	}
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		_, err := c.GetValue("other")
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("value outside of root key is accessible: %v", err)
+		}
+	}
+
+	c, err = NewBuilder().AddMap(m).SetRootKey("app:db").Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("host")
+		assertVal(t, v, err, "localhost")
+	}
+
+	for _, key := range []string{"missing", "other", "app:port"} {
+		_, err = NewBuilder().AddMap(m).SetRootKey(key).Build()
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("root key %q did not return ErrNil: %v", key, err)
+		}
+	}
+}
+
+func TestWithSectionDefaults(t *testing.T) {
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{m: map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"timeout": 30,
+				"retry":   map[string]interface{}{"count": 3, "delay": 1},
+			},
+			"workers": map[string]interface{}{
+				"a":     map[string]interface{}{"name": "a"},
+				"b":     map[string]interface{}{"name": "b", "retry": map[string]interface{}{"count": 5}},
+				"c":     map[string]interface{}{"name": "c", "timeout": 10},
+				"count": 3,
+			},
+		}}).
+		WithSectionDefaults("defaults", "workers").
+		WithSectionDefaults("missing", "workers").
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetInt("workers:a:timeout")
+		assertVal(t, v, err, 30)
+	}
+	{
+		v, err := c.GetInt("workers:b:timeout")
+		assertVal(t, v, err, 30)
+	}
+	{
+		v, err := c.GetInt("workers:c:timeout")
+		assertVal(t, v, err, 10)
+	}
+	{
+		v, err := c.GetInt("workers:b:retry:count")
+		assertVal(t, v, err, 5)
+	}
+	{
+		v, err := c.GetInt("workers:b:retry:delay")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := c.GetInt("workers:a:retry:count")
+		assertVal(t, v, err, 3)
+	}
+	{
+		v, err := c.GetInt("workers:count")
+		assertVal(t, v, err, 3)
+	}
+	{
+		v, err := c.GetInt("defaults:retry:count")
+		assertVal(t, v, err, 3)
+	}
+}
+
+func TestWithInlineDefaultsSection(t *testing.T) {
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{m: map[string]interface{}{
+			"_defaults": map[string]interface{}{
+				"level": "info",
+				"db":    map[string]interface{}{"host": "localhost", "port": 5432},
+				"cache": map[string]interface{}{"ttl": 30},
+			},
+			"db": map[string]interface{}{"host": "db.example.com"},
+		}}).
+		WithInlineDefaultsSection("_defaults").
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "db.example.com")
+	}
+	{
+		v, err := c.GetInt("db:port")
+		assertVal(t, v, err, 5432)
+	}
+	{
+		v, err := c.GetString("level")
+		assertVal(t, v, err, "info")
+	}
+	{
+		v, err := c.GetInt("cache:ttl")
+		assertVal(t, v, err, 30)
+	}
+	{
+		v, err := c.GetSection("db").GetInt("port")
+		assertVal(t, v, err, 5432)
+	}
+	{
+		v, err := c.GetSection("db").WithPrefix("").GetInt("port")
+		assertVal(t, v, err, 5432)
+	}
+	for _, key := range []string{"db:user", "_defaults:user"} {
+		if _, err := c.GetString(key); !errors.Is(err, ErrNil) {
+			t.Errorf("error (%v) of %q did not match ErrNil", err, key)
+		}
+	}
+
+	// defaults are only used on reads
+	assertSlice(t, c.Keys(), nil, []string{"_defaults", "db"})
+	assertSlice(t, c.GetSection("db").Keys(), nil, []string{"host"})
+
+	if _, err := c.GetOrSet("db:port", func() (interface{}, error) { return 6432, nil }); err != nil {
+		t.Fatalf("get or set failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("db:port")
+		assertVal(t, v, err, 6432)
+	}
+	{
+		v, err := c.GetInt("_defaults:db:port")
+		assertVal(t, v, err, 5432)
+	}
+}
+
+func TestStrictTypes(t *testing.T) {
+	m := map[string]interface{}{
+		"str":     "5",
+		"int":     5,
+		"float":   5.0,
+		"half":    5.5,
+		"bool":    true,
+		"strbool": "true",
+	}
+
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{m: m}).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("str")
+		assertVal(t, v, err, 5)
+	}
+	{
+		v, err := c.GetBool("strbool")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetString("int")
+		assertVal(t, v, err, "5")
+	}
+
+	c, err = NewBuilder().
+		AddProvider(&mockProvider{m: m}).
+		StrictTypes().
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("int")
+		assertVal(t, v, err, 5)
+	}
+	{
+		v, err := c.GetInt("float")
+		assertVal(t, v, err, 5)
+	}
+	{
+		v, err := c.GetFloat64("int")
+		assertVal(t, v, err, 5.0)
+	}
+	{
+		v, err := c.GetString("str")
+		assertVal(t, v, err, "5")
+	}
+	{
+		v, err := c.GetBool("bool")
+		assertVal(t, v, err, true)
+	}
+
+	failing := []func() error{
+		func() error { _, err := c.GetInt("str"); return err },
+		func() error { _, err := c.GetInt("half"); return err },
+		func() error { _, err := c.GetInt32("str"); return err },
+		func() error { _, err := c.GetFloat64("str"); return err },
+		func() error { _, err := c.GetBool("strbool"); return err },
+		func() error { _, err := c.GetString("int"); return err },
+	}
+	for i, fn := range failing {
+		if err := fn(); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("error %d (%v) did not match ErrInvalidType", i, err)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	base := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test1.json", false)
+
+	c1 := base.Clone().
+		AddProvider(&mockProvider{m: map[string]interface{}{"tenant": "a"}})
+	c2 := base.Clone().
+		SetBasePath("other").
+		AddProvider(&mockProvider{m: map[string]interface{}{"tenant": "b"}}).
+		AddProvider(&mockProvider{})
+
+	if len(base.provider) != 1 || len(c1.provider) != 2 || len(c2.provider) != 3 {
+		t.Fatalf("provider counts (%d, %d, %d) were not like expected",
+			len(base.provider), len(c1.provider), len(c2.provider))
+	}
+	assert(t, base.basePath, "testdata")
+	assert(t, c1.basePath, "testdata")
+
+	for expected, b := range map[string]*Builder{"a": c1, "b": c2} {
+		c, err := b.Build()
+		if err != nil {
+			t.Fatalf("build failed: %s", err.Error())
+		}
+		v, err := c.GetString("tenant")
+		assertVal(t, v, err, expected)
+	}
+}
+
 func TestAddJsonFile(t *testing.T) {
 	b := NewBuilder().
 		AddJsonFile("file.json", false)
@@ -47,86 +534,2115 @@ func TestAddJsonFile(t *testing.T) {
 	}
 }
 
-func TestAddYamlFile(t *testing.T) {
-	b := NewBuilder().
-		AddYamlFile("file.yaml", false)
+func TestAddJsonFileTransform(t *testing.T) {
+	rename := func(m ConfigMap) (ConfigMap, error) {
+		m["c"] = m["a"]
+		delete(m, "a")
+		return m, nil
+	}
 
-	if b == nil {
-		t.Error("returned builder instance was nil")
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFileTransform("test1.json", false, rename).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
 	}
-	if len(b.provider) != 1 || b.provider[0] == nil {
-		t.Error("providers array is empty")
+	{
+		v, err := c.GetString("c")
+		assertVal(t, v, err, "test3")
 	}
-	v, ok := b.provider[0].(*providers.YamlProvider)
-	if !ok || v == nil {
-		t.Error("added provider is no YamlProvider")
+	{
+		_, err := c.GetValue("a")
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("renamed key is still present: %v", err)
+		}
+	}
+	{
+		v, err := c.GetInt("b:e")
+		assertVal(t, v, err, 3)
 	}
-}
 
-func TestAddEnvironmentVariables(t *testing.T) {
-	b := NewBuilder().
-		AddEnvironmentVariables("TEST_", false)
+	errTransform := errors.New("transform failed")
+	fail := func(m ConfigMap) (ConfigMap, error) {
+		return nil, errTransform
+	}
 
-	if b == nil {
-		t.Error("returned builder instance was nil")
+	_, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFileTransform("test1.json", false, fail).
+		Build()
+	var sErr *SourceError
+	if !errors.As(err, &sErr) || !errors.Is(err, errTransform) {
+		t.Errorf("required source did not return transform error: %v", err)
 	}
-	if len(b.provider) != 1 || b.provider[0] == nil {
-		t.Error("providers array is empty")
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test2.json", false).
+		AddJsonFileTransform("test1.json", true, fail).
+		Build()
+	if err != nil {
+		t.Fatalf("optional source returned transform error: %v", err)
 	}
-	v, ok := b.provider[0].(*providers.EnvProvider)
-	if !ok || v == nil {
-		t.Error("added provider is no EnvProvider")
+	if c.GetStringOrDef("a", "") == "test3" {
+		t.Error("values of failed optional source were merged")
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test2.json", false).
+		AddJsonFileTransform("does_not_exist.json", true, rename).
+		Build()
+	if err != nil {
+		t.Fatalf("missing optional source returned error: %v", err)
+	}
+	if _, err = c.GetValue("c"); !errors.Is(err, ErrNil) {
+		t.Errorf("missing optional source was transformed: %v", err)
 	}
 }
 
-func TestBuild(t *testing.T) {
-	os.Setenv("TEST_b__f", "2")
+func TestPlan(t *testing.T) {
+	plan := NewBuilder().
+		SetBasePath("config").
+		AddJsonFile("app.json", false).
+		AddYamlFile("local.yaml", true).
+		If(func() bool {
+			t.Error("condition was evaluated")
+			return false
+		}).
+		AddEnvironmentVariables("APP_", true).
+		AddProvider(&mockProvider{}).
+		Plan()
 
-	sec, err := NewBuilder().
-		SetBasePath("./testdata").
-		AddJsonFile("test1.json", false).
+	expected := []SourcePlan{
+		{Type: "json", Source: path.Join("config", "app.json")},
+		{Type: "yaml", Source: path.Join("config", "local.yaml"), Optional: true, Conditional: true},
+		{Type: "env", Source: "env:APP_"},
+		{Type: "*configoration.mockProvider", Source: "*configoration.mockProvider"},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("plan (%+v) was not like expected (%+v)", plan, expected)
+	}
+}
+
+func TestIf(t *testing.T) {
+	production := false
+	var empty []string
+	b := NewBuilder().
+		WarnOnEmptySources(func(source string) {
+			empty = append(empty, source)
+		}).
+		SetBasePath("testdata").
 		AddJsonFile("test2.json", false).
-		AddYamlFile("test3.yaml", false).
-		AddEnvironmentVariables("TEST_", false).
-		Build()
+		AddJsonFile("test1.json", false).
+		If(func() bool { return production })
 
+	c, err := b.Build()
 	if err != nil {
-		t.Errorf("build failed: %s", err.Error())
+		t.Fatalf("build failed: %s", err.Error())
 	}
-
 	{
-		v, err := sec.GetString("a")
+		v, err := c.GetString("a")
 		assertVal(t, v, err, "test2")
 	}
 	{
-		v, err := sec.GetInt("b:b")
-		assertVal(t, v, err, 1)
+		_, err := c.GetValue("b:e")
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("key of excluded source is present: %v", err)
+		}
 	}
-	{
-		v, err := sec.GetInt("b:c")
-		assertVal(t, v, err, 11112)
+	if len(empty) != 0 {
+		t.Errorf("excluded source was reported as empty: %v", empty)
 	}
-	{
-		v, err := sec.GetInt("b:e")
-		assertVal(t, v, err, 3)
+
+	production = true
+	c, err = b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
 	}
 	{
-		v, err := sec.GetInt("b:f")
-		assertVal(t, v, err, 2)
+		v, err := c.GetString("a")
+		assertVal(t, v, err, "test3")
 	}
 	{
-		v, err := sec.GetBool("g:e:f")
-		assertVal(t, v, err, true)
+		v, err := c.GetInt("b:e")
+		assertVal(t, v, err, 3)
 	}
-	{
-		v, err := sec.GetInt("y:e")
-		assertVal(t, v, err, 1123)
+
+	// conditional file sources keep their file
+	// name only while they are included
+	fp, ok := b.provider[1].(FileProvider)
+	if !ok {
+		t.Fatal("conditional provider is no file provider")
 	}
-}
+	assert(t, fp.FileName(), "testdata/test1.json")
+	production = false
+	assert(t, fp.FileName(), "")
 
-// --------------------------------------------------------------------------
+	// If is a no-op if the previous call added
+	// no source
+	c, err = NewBuilder().
+		AddMap(NewConfigMap().Set("a", 1)).
+		AddFile("config.hcl", true).
+		If(func() bool { return false }).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("a")
+		assertVal(t, v, err, 1)
+	}
+}
+
+func TestAddJsonFileE(t *testing.T) {
+	b, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFileE("test1.json", false)
+	if err != nil {
+		t.Fatalf("existing file returned error: %v", err)
+	}
+	if len(b.provider) != 1 {
+		t.Error("provider was not added")
+	}
+
+	b, err = NewBuilder().
+		AddJsonFileE("testdata/missing.json", false)
+	var sErr *SourceError
+	if !errors.As(err, &sErr) || !os.IsNotExist(sErr.Err) {
+		t.Errorf("missing file did not return not exist error: %v", err)
+	}
+	if len(b.provider) != 0 {
+		t.Error("provider was added for missing file")
+	}
+
+	b, err = NewBuilder().
+		AddJsonFileE("testdata/missing.json", true)
+	if err != nil {
+		t.Errorf("missing optional file returned error: %v", err)
+	}
+	if len(b.provider) != 1 {
+		t.Error("optional provider was not added")
+	}
+}
+
+func TestMustAddJsonFile(t *testing.T) {
+	b := NewBuilder().
+		MustAddJsonFile("testdata/test1.json")
+	if len(b.provider) != 1 {
+		t.Error("provider was not added")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("missing file did not panic")
+		}
+	}()
+	NewBuilder().MustAddJsonFile("testdata/missing.json")
+}
+
+func TestAddJsonFileFromConfigDir(t *testing.T) {
+	configHome, err := ioutil.TempDir("", "configoration")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(configHome)
+
+	oldConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+	defer os.Setenv("XDG_CONFIG_HOME", oldConfigHome)
+
+	appDir := filepath.Join(configHome, "myapp")
+	if err = os.Mkdir(appDir, 0755); err != nil {
+		t.Fatalf("creating dir failed: %s", err.Error())
+	}
+	err = ioutil.WriteFile(filepath.Join(appDir, "config.json"), []byte(`{"a": "configdir"}`), 0644)
+	if err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+
+	sec, err := NewBuilder().
+		SetConfigDirName("myapp").
+		AddJsonFileFromConfigDir("config.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	v, err := sec.GetString("a")
+	assertVal(t, v, err, "configdir")
+
+	_, err = NewBuilder().
+		SetConfigDirName("myapp").
+		AddJsonFileFromConfigDir("none.json", true).
+		Build()
+	if err != nil {
+		t.Errorf("optional missing file errored: %s", err.Error())
+	}
+
+	_, err = NewBuilder().
+		SetConfigDirName("myapp").
+		AddJsonFileFromConfigDir("none.json", false).
+		Build()
+	if !os.IsNotExist(errors.Unwrap(err)) {
+		t.Errorf("required missing file did not fail: %v", err)
+	}
+}
+
+func TestAddYamlFile(t *testing.T) {
+	b := NewBuilder().
+		AddYamlFile("file.yaml", false)
+
+	if b == nil {
+		t.Error("returned builder instance was nil")
+	}
+	if len(b.provider) != 1 || b.provider[0] == nil {
+		t.Error("providers array is empty")
+	}
+	v, ok := b.provider[0].(*providers.YamlProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no YamlProvider")
+	}
+}
+
+func TestSetAllowEmptyFiles(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		SetAllowEmptyFiles(true).
+		AddJsonFile("test1.json", false).
+		AddJsonFile("empty.json", false).
+		AddJsonFileLazy("empty.json", false).
+		AddYamlFile("empty.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	if len(c.Sources()) != 4 {
+		t.Errorf("sources (%+v) were not like expected", c.Sources())
+	}
+
+	_, err = NewBuilder().
+		SetBasePath("testdata").
+		SetAllowEmptyFiles(true).
+		AddJsonFile("malformed.json", false).
+		Build()
+	if err == nil {
+		t.Error("malformed file did not return an error")
+	}
+
+	for _, b := range []*Builder{
+		NewBuilder().AddJsonFile("testdata/empty.json", false),
+		NewBuilder().AddYamlFile("testdata/empty.yaml", false),
+	} {
+		if _, err = b.Build(); err == nil {
+			t.Error("empty file did not return an error without SetAllowEmptyFiles")
+		}
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("bom.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("a")
+		assertVal(t, v, err, "bom")
+	}
+	{
+		v, err := c.GetInt("b:c")
+		assertVal(t, v, err, 1)
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddYamlFile("utf16.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("a")
+		assertVal(t, v, err, "utf16")
+	}
+	{
+		v, err := c.GetString("b:c")
+		assertVal(t, v, err, "ü")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var events []Event
+	_, err := NewBuilder().
+		SetBasePath("testdata").
+		WithLogger(func(event Event) {
+			events = append(events, event)
+		}).
+		AddJsonFile("test1.json", false).
+		AddMap(NewConfigMap().Set("b:e", 4)).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("events (%+v) were not like expected", events)
+	}
+	assert(t, events[0].Type, EventSourceLoaded)
+	assert(t, events[0].Source, "testdata/test1.json")
+	assert(t, events[1].Type, EventSourceLoaded)
+	assert(t, events[1].Source, "map")
+	assert(t, events[2].Type, EventKeyOverridden)
+	assert(t, events[2].Key, "b:e")
+	assert(t, events[2].Source, "map")
+	assert(t, events[2].OverriddenSource, "testdata/test1.json")
+	assert(t, events[3].Type, EventBuilt)
+	if events[3].Duration <= 0 {
+		t.Error("build event has no duration")
+	}
+
+	events = nil
+	_, err = NewBuilder().
+		WithLogger(func(event Event) {
+			events = append(events, event)
+		}).
+		AddJsonFile("does_not_exist.json", false).
+		Build()
+	if err == nil {
+		t.Fatal("missing file did not return an error")
+	}
+	if len(events) != 1 || events[0].Type != EventSourceFailed || events[0].Err == nil {
+		t.Errorf("events (%+v) were not like expected", events)
+	}
+}
+
+func TestWarnOnEmptySources(t *testing.T) {
+	var empty []string
+	_, err := NewBuilder().
+		SetBasePath("testdata").
+		SetAllowEmptyFiles(true).
+		WarnOnEmptySources(func(source string) {
+			empty = append(empty, source)
+		}).
+		AddJsonFile("test1.json", false).
+		AddJsonFile("empty.json", false).
+		AddYamlFile("empty.yaml", true).
+		AddJsonFile("does_not_exist.json", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	assertSlice(t, empty, nil, []string{"testdata/empty.json"})
+
+	_, err = NewBuilder().
+		SetBasePath("testdata").
+		SetAllowEmptyFiles(true).
+		SetFailOnEmptySources(true).
+		AddJsonFile("test1.json", false).
+		AddJsonFile("empty.json", false).
+		Build()
+	if !errors.Is(err, ErrEmptySource) {
+		t.Errorf("build returned %v instead of ErrEmptySource", err)
+	}
+	var serr *SourceError
+	if !errors.As(err, &serr) || serr.Source != "testdata/empty.json" {
+		t.Errorf("error (%v) did not name the empty source", err)
+	}
+
+	empty = nil
+	_, err = NewBuilder().
+		SetFailOnEmptySources(true).
+		WarnOnEmptySources(func(source string) {
+			empty = append(empty, source)
+		}).
+		AddMap(map[string]interface{}{}).
+		AddEnvironmentVariables("TESTUNUSEDPREFIX_", false).
+		Build()
+	if err != nil {
+		t.Errorf("build with unused env prefix failed: %s", err.Error())
+	}
+	if len(empty) != 0 {
+		t.Errorf("empty sources (%+v) were reported", empty)
+	}
+}
+
+func TestLazyInterpolation(t *testing.T) {
+	os.Setenv("TESTLAZY_HOST", "first")
+	defer os.Unsetenv("TESTLAZY_HOST")
+
+	m := NewConfigMap().
+		Set("url", "http://${TESTLAZY_HOST}:${TESTLAZY_PORT}/").
+		Set("open", "${TESTLAZY_HOST")
+
+	c, err := NewBuilder().
+		AddMap(m).
+		LazyInterpolation().
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("url")
+		assertVal(t, v, err, "http://first:/")
+	}
+
+	os.Setenv("TESTLAZY_HOST", "second")
+	os.Setenv("TESTLAZY_PORT", "80")
+	defer os.Unsetenv("TESTLAZY_PORT")
+
+	{
+		v, err := c.GetString("url")
+		assertVal(t, v, err, "http://second:80/")
+	}
+	{
+		v, err := c.WithPrefix("").GetString("url")
+		assertVal(t, v, err, "http://second:80/")
+	}
+	{
+		v, err := c.GetString("open")
+		assertVal(t, v, err, "${TESTLAZY_HOST")
+	}
+	{
+		v, err := c.GetValue("url")
+		assertVal(t, v, err, "http://${TESTLAZY_HOST}:${TESTLAZY_PORT}/")
+	}
+
+	c, err = NewBuilder().AddMap(m).Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("url")
+		assertVal(t, v, err, "http://${TESTLAZY_HOST}:${TESTLAZY_PORT}/")
+	}
+}
+
+func TestSetFileDirectives(t *testing.T) {
+	pem, err := ioutil.ReadFile("testdata/directive/key.pem")
+	if err != nil {
+		t.Fatalf("reading file failed: %s", err.Error())
+	}
+
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		SetFileDirectives(true).
+		AddJsonFile("directive/config.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("tls:key")
+		assertVal(t, v, err, string(pem))
+	}
+	{
+		v, err := c.GetString("tls:cert:$file")
+		assertVal(t, v, err, "key.pem")
+	}
+	{
+		v, err := c.GetString("keys:0")
+		assertVal(t, v, err, string(pem))
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		SetFileDirectives(true).
+		AddYamlFile("directive/config.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("tls:key")
+		assertVal(t, v, err, string(pem))
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("directive/config.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("tls:key:$file")
+		assertVal(t, v, err, "key.pem")
+	}
+
+	_, err = NewBuilder().
+		SetBasePath("testdata").
+		SetFileDirectives(true).
+		AddJsonFile("directive/missing.json", false).
+		Build()
+	if err == nil {
+		t.Error("missing referenced file did not return an error")
+	}
+}
+
+func TestSetYaml12Booleans(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		SetYaml12Booleans(true).
+		AddYamlFile("yaml11.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("country")
+		assertVal(t, v, err, "no")
+	}
+	{
+		v, err := c.GetValue("enabled")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetValue("toggle")
+		assertVal(t, v, err, "on")
+	}
+	{
+		v, err := c.GetString("nested:answer")
+		assertVal(t, v, err, "yes")
+	}
+	{
+		v, err := c.GetValue("nested:list")
+		assertSlice(t, v, err, []interface{}{"off", false})
+	}
+	if c.GetSection("nested").IsNil() {
+		t.Error("nested section was nil")
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddYamlFile("yaml11.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetValue("country")
+		assertVal(t, v, err, false)
+	}
+}
+
+func TestAddEnvironmentVariables(t *testing.T) {
+	b := NewBuilder().
+		AddEnvironmentVariables("TEST_", false)
+
+	if b == nil {
+		t.Error("returned builder instance was nil")
+	}
+	if len(b.provider) != 1 || b.provider[0] == nil {
+		t.Error("providers array is empty")
+	}
+	v, ok := b.provider[0].(*providers.EnvProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no EnvProvider")
+	}
+}
+
+func TestAddEnvironmentVariablesEmptyAsUnset(t *testing.T) {
+	os.Setenv("TEST_FOO", "")
+	defer os.Unsetenv("TEST_FOO")
+
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("foo.json", false).
+		AddEnvironmentVariables("TEST_", true, providers.TreatEmptyEnvAsUnset()).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("foo")
+		assertVal(t, v, err, "file")
+	}
+
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("foo.json", false).
+		AddEnvironmentVariables("TEST_", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("foo")
+		assertVal(t, v, err, "")
+	}
+}
+
+func TestAddEnvironmentVariablesPrefixCaseFold(t *testing.T) {
+	os.Setenv("TESTFOLD_UPPER", "a")
+	os.Setenv("testfold_lower", "b")
+	os.Setenv("TestFold_Mixed__Key", "c")
+	defer func() {
+		for _, k := range []string{"TESTFOLD_UPPER", "testfold_lower", "TestFold_Mixed__Key"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c, err := NewBuilder().
+		AddEnvironmentVariables("TESTFOLD_", false, providers.WithPrefixCaseFold()).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("UPPER")
+		assertVal(t, v, err, "a")
+	}
+	{
+		v, err := c.GetString("lower")
+		assertVal(t, v, err, "b")
+	}
+	{
+		v, err := c.GetString("Mixed:Key")
+		assertVal(t, v, err, "c")
+	}
+
+	c, err = NewBuilder().
+		AddEnvironmentVariables("TESTFOLD_", true, providers.WithPrefixCaseFold()).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("mixed:key")
+		assertVal(t, v, err, "c")
+	}
+
+	c, err = NewBuilder().
+		AddEnvironmentVariables("TESTFOLD_", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	assertSlice(t, c.Keys(), nil, []string{"UPPER"})
+}
+
+func TestAddEnvironmentVariablesNestingSeparator(t *testing.T) {
+	vars := map[string]string{
+		"TESTSEP_DB__CONNECTION_STRING":   "default",
+		"TESTSEP_LOG_LEVEL":               "debug",
+		"TESTSEP_API___KEY__ID":           "custom",
+		"TESTSEP_CACHE___MAX_SIZE":        "10",
+		"TESTSEP_NO__NESTING":             "flat",
+		"TESTSEPFLAT_NO__NESTING":         "flat",
+		"TESTSEPFLAT_STILL__NOT___NESTED": "flat",
+	}
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c, err := NewBuilder().
+		AddEnvironmentVariables("TESTSEP_", true).
+		AddEnvironmentVariables("TESTSEP_", true, providers.WithNestingSeparator("___")).
+		AddEnvironmentVariables("TESTSEPFLAT_", true, providers.WithNestingSeparator("")).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	// single underscores are kept as part of
+	// the key with the default separator
+	{
+		v, err := c.GetString("db:connection_string")
+		assertVal(t, v, err, "default")
+	}
+	{
+		v, err := c.GetString("log_level")
+		assertVal(t, v, err, "debug")
+	}
+	{
+		v, err := c.GetString("no:nesting")
+		assertVal(t, v, err, "flat")
+	}
+
+	// with "___" as separator, "__" is kept
+	// as part of the key
+	{
+		v, err := c.GetString("api:key__id")
+		assertVal(t, v, err, "custom")
+	}
+	{
+		v, err := c.GetInt("cache:max_size")
+		assertVal(t, v, err, 10)
+	}
+
+	// an empty separator disables nesting
+	{
+		v, err := c.GetString("no__nesting")
+		assertVal(t, v, err, "flat")
+	}
+	{
+		v, err := c.GetString("still__not___nested")
+		assertVal(t, v, err, "flat")
+	}
+}
+
+func TestAddEnvironmentVariablesIntoSection(t *testing.T) {
+	vars := map[string]string{
+		"TESTSEC_PORT":     "8080",
+		"TESTSEC_DB__HOST": "env-host",
+	}
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c, err := NewBuilder().
+		AddMap(NewConfigMap().Set("port", 80).Set("db:host", "file-host")).
+		AddEnvironmentVariables("TESTSEC_", true, providers.IntoSection("env")).
+		AddEnvironmentVariables("TESTSEC_", true, providers.IntoSection("sources:env")).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetInt("port")
+		assertVal(t, v, err, 80)
+	}
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "file-host")
+	}
+	{
+		v, err := c.GetInt("env:port")
+		assertVal(t, v, err, 8080)
+	}
+	{
+		v, err := c.GetString("env:db:host")
+		assertVal(t, v, err, "env-host")
+	}
+	{
+		v, err := c.GetString("sources:env:db:host")
+		assertVal(t, v, err, "env-host")
+	}
+}
+
+func TestAddEnvironmentVariablesIndexedArrays(t *testing.T) {
+	vars := map[string]string{
+		"TESTIDX_ITEM_2":         "c",
+		"TESTIDX_ITEM_0":         "a",
+		"TESTIDX_ITEM_1":         "b",
+		"TESTIDX_GAP_3":          "y",
+		"TESTIDX_GAP_0":          "x",
+		"TESTIDX_DB__HOSTS_0":    "h0",
+		"TESTIDX_DB__HOSTS_1":    "h1",
+		"TESTIDX_USERS_0__NAME":  "alice",
+		"TESTIDX_PORT":           "80",
+		"TESTIDX_PORT_1":         "81",
+		"TESTIDX_NOT_INDEXED_1A": "z",
+	}
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c, err := NewBuilder().
+		AddEnvironmentVariables("TESTIDX_", true, providers.WithIndexedArrays()).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetStringSlice("item")
+		assertSlice(t, v, err, []string{"a", "b", "c"})
+	}
+	{
+		v, err := c.GetStringSlice("gap")
+		assertSlice(t, v, err, []string{"x", "y"})
+	}
+	{
+		v, err := c.GetStringSlice("db:hosts")
+		assertSlice(t, v, err, []string{"h0", "h1"})
+	}
+	{
+		v, err := c.GetString("users:0:name")
+		assertVal(t, v, err, "alice")
+	}
+	{
+		v, err := c.GetString("port")
+		assertVal(t, v, err, "80")
+		v, err = c.GetString("port_1")
+		assertVal(t, v, err, "81")
+	}
+	{
+		v, err := c.GetString("not_indexed_1a")
+		assertVal(t, v, err, "z")
+	}
+
+	c, err = NewBuilder().
+		AddEnvironmentVariables("TESTIDX_", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("item_0")
+		assertVal(t, v, err, "a")
+	}
+}
+
+func TestAddEnvironmentVariablesFileSecrets(t *testing.T) {
+	f, err := ioutil.TempFile("", "configoration")
+	if err != nil {
+		t.Fatalf("creating temp file failed: %s", err.Error())
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString("s3cr3t\n"); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+	f.Close()
+
+	os.Setenv("TESTSEC_SECRET_FILE", f.Name())
+	os.Setenv("TESTSEC_DB__PASSWORD_FILE", f.Name())
+	os.Setenv("TESTSEC_OVERRIDE", "direct")
+	os.Setenv("TESTSEC_OVERRIDE_FILE", f.Name())
+	defer func() {
+		for _, k := range []string{"SECRET_FILE", "DB__PASSWORD_FILE", "OVERRIDE", "OVERRIDE_FILE"} {
+			os.Unsetenv("TESTSEC_" + k)
+		}
+	}()
+
+	c, err := NewBuilder().
+		AddEnvironmentVariables("TESTSEC_", true, providers.WithFileSecrets()).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("secret")
+		assertVal(t, v, err, "s3cr3t")
+	}
+	{
+		v, err := c.GetString("db:password")
+		assertVal(t, v, err, "s3cr3t")
+	}
+	{
+		v, err := c.GetString("override")
+		assertVal(t, v, err, "direct")
+	}
+	{
+		_, err := c.GetValue("secret_file")
+		if !errors.Is(err, ErrNil) {
+			t.Error("file variable was not removed")
+		}
+	}
+
+	c, err = NewBuilder().
+		AddEnvironmentVariables("TESTSEC_", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("secret_file")
+		assertVal(t, v, err, f.Name())
+	}
+
+	os.Setenv("TESTSEC_MISSING_FILE", f.Name()+".missing")
+	defer os.Unsetenv("TESTSEC_MISSING_FILE")
+	_, err = NewBuilder().
+		AddEnvironmentVariables("TESTSEC_", true, providers.WithFileSecrets()).
+		Build()
+	if err == nil {
+		t.Error("missing secret file did not return an error")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	os.Setenv("TEST_b__f", "2")
+
+	sec, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("test1.json", false).
+		AddJsonFile("test2.json", false).
+		AddYamlFile("test3.yaml", false).
+		AddEnvironmentVariables("TEST_", false).
+		Build()
+
+	if err != nil {
+		t.Errorf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("a")
+		assertVal(t, v, err, "test2")
+	}
+	{
+		v, err := sec.GetInt("b:b")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := sec.GetInt("b:c")
+		assertVal(t, v, err, 11112)
+	}
+	{
+		v, err := sec.GetInt("b:e")
+		assertVal(t, v, err, 3)
+	}
+	{
+		v, err := sec.GetInt("b:f")
+		assertVal(t, v, err, 2)
+	}
+	{
+		v, err := sec.GetBool("g:e:f")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := sec.GetInt("y:e")
+		assertVal(t, v, err, 1123)
+	}
+}
+
+func TestAddJsonFileLazy(t *testing.T) {
+	os.Setenv("LAZY_b__f", "2")
+	defer os.Unsetenv("LAZY_b__f")
+
+	c, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFileLazy("test1.json", false).
+		AddJsonFileLazy("test2.json", false).
+		AddEnvironmentVariables("LAZY_", false).
+		Build()
+
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	root := c.(*config).section
+	if _, ok := root.m["g"].(*providers.LazyValue); !ok {
+		t.Error("untouched subtree was decoded on build")
+	}
+
+	{
+		v, err := c.GetString("a")
+		assertVal(t, v, err, "test2")
+	}
+	{
+		v, err := c.GetInt("b:b")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := c.GetInt("b:c")
+		assertVal(t, v, err, 11112)
+	}
+	{
+		v, err := c.GetInt("b:f")
+		assertVal(t, v, err, 2)
+	}
+	{
+		v, err := c.GetBool("g:e:f")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetBool("g:e:f")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetValue("g")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		assertSlice(t, v, nil, ConfigMap{"e": ConfigMap{"f": true}})
+	}
+}
+
+func TestBuildStrictParsing(t *testing.T) {
+	for _, fileName := range []string{"duplicate.json", "duplicate.yaml"} {
+		b := NewBuilder().
+			SetBasePath("./testdata").
+			SetStrictParsing(true)
+
+		if path.Ext(fileName) == ".json" {
+			b.AddJsonFile(fileName, false)
+		} else {
+			b.AddYamlFile(fileName, false)
+		}
+
+		_, err := b.Build()
+		if err == nil {
+			t.Errorf("build of %s returned no error", fileName)
+			continue
+		}
+
+		var dupErr *providers.DuplicateKeyError
+		if !errors.As(err, &dupErr) {
+			t.Errorf("build of %s returned unexpected error: %s", fileName, err.Error())
+			continue
+		}
+		if dupErr.Key != "b:c" {
+			t.Errorf("duplicate key (%+v) was not like expected (%+v)", dupErr.Key, "b:c")
+		}
+		if dupErr.File != path.Join("testdata", fileName) {
+			t.Errorf("duplicate file (%+v) was not like expected (%+v)",
+				dupErr.File, path.Join("testdata", fileName))
+		}
+	}
+
+	sec, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("duplicate.json", false).
+		Build()
+	if err != nil {
+		t.Errorf("non-strict build failed: %s", err.Error())
+	}
+	v, err := sec.GetInt("b:c")
+	assertVal(t, v, err, 2)
+}
+
+func TestWithConflictReporter(t *testing.T) {
+	os.Setenv("CONFLICT_a", "env")
+	defer os.Unsetenv("CONFLICT_a")
+
+	type conflict struct {
+		key, fromSource, overriddenSource string
+	}
+	var conflicts []conflict
+
+	sec, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("test1.json", false).
+		AddEnvironmentVariables("CONFLICT_", false).
+		WithConflictReporter(func(key, fromSource, overriddenSource string) {
+			conflicts = append(conflicts, conflict{key, fromSource, overriddenSource})
+		}).
+		Build()
+
+	if err != nil {
+		t.Errorf("build failed: %s", err.Error())
+	}
+
+	v, err := sec.GetString("a")
+	assertVal(t, v, err, "env")
+
+	if len(conflicts) != 1 {
+		t.Fatalf("reported conflicts (%+v) were not like expected (1)", conflicts)
+	}
+	expected := conflict{"a", "env:CONFLICT_", "testdata/test1.json"}
+	if conflicts[0] != expected {
+		t.Errorf("reported conflict (%+v) was not like expected (%+v)", conflicts[0], expected)
+	}
+}
+
+func TestBuildTypeConflict(t *testing.T) {
+	{
+		_, err := NewBuilder().
+			SetBasePath("./testdata").
+			SetStrictMerge(true).
+			AddJsonFile("test1.json", false).
+			AddJsonFile("typeconflict.json", false).
+			Build()
+
+		if !errors.Is(err, ErrTypeConflict) {
+			t.Fatalf("scalar replaced by section did not return ErrTypeConflict: %v", err)
+		}
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || keyErr.Key != "a" {
+			t.Errorf("type conflict error (%+v) did not carry the key", err)
+		}
+	}
+	{
+		_, err := NewBuilder().
+			SetBasePath("./testdata").
+			SetStrictMerge(true).
+			AddJsonFile("test1.json", false).
+			AddProvider(&mockProvider{m: map[string]interface{}{"b": 5}}).
+			Build()
+
+		if !errors.Is(err, ErrTypeConflict) {
+			t.Fatalf("section replaced by scalar did not return ErrTypeConflict: %v", err)
+		}
+	}
+	{
+		var reported []string
+		sec, err := NewBuilder().
+			SetBasePath("./testdata").
+			AddJsonFile("test1.json", false).
+			AddJsonFile("typeconflict.json", false).
+			WithConflictReporter(func(key, fromSource, overriddenSource string) {
+				reported = append(reported, key, fromSource, overriddenSource)
+			}).
+			Build()
+
+		if err != nil {
+			t.Fatalf("non-strict build failed: %s", err.Error())
+		}
+		if len(reported) != 3 || reported[0] != "a" ||
+			reported[1] != "testdata/typeconflict.json" || reported[2] != "testdata/test1.json" {
+			t.Errorf("reported conflict (%+v) was not like expected", reported)
+		}
+		v, err := sec.GetSection("a").GetInt("x")
+		assertVal(t, v, err, 1)
+	}
+}
+
+func TestWithKeyNormalizer(t *testing.T) {
+	var collisions []string
+
+	sec, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("kebab.json", false).
+		WithKeyNormalizer(func(key string) string {
+			return strings.Replace(key, "-", "_", -1)
+		}).
+		WithConflictReporter(func(key, fromSource, overriddenSource string) {
+			collisions = append(collisions, key, fromSource, overriddenSource)
+		}).
+		Build()
+
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("a_b")
+		assertVal(t, v, err, "kebab")
+	}
+	{
+		v, err := sec.GetString("a-b")
+		assertVal(t, v, err, "kebab")
+	}
+	{
+		v, err := sec.GetInt("c_d:e_f")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := sec.GetSection("c-d").GetInt("e-f")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := sec.GetInt("g_h")
+		assertVal(t, v, err, 1)
+	}
+
+	expected := []string{"g_h", "testdata/kebab.json", "testdata/kebab.json"}
+	if len(collisions) != 3 || collisions[0] != expected[0] ||
+		collisions[1] != expected[1] || collisions[2] != expected[2] {
+		t.Errorf("reported collisions (%+v) were not like expected (%+v)", collisions, expected)
+	}
+}
+
+func TestAddStruct(t *testing.T) {
+	type inner struct {
+		B int `config:"b"`
+		X int `config:"x"`
+	}
+	defaults := struct {
+		A       string `config:"a"`
+		B       inner  `config:"b"`
+		Port    int
+		Ignored string `config:"-"`
+	}{
+		A:       "default",
+		B:       inner{B: 10, X: 20},
+		Port:    8080,
+		Ignored: "ignored",
+	}
+
+	b := NewBuilder().
+		SetBasePath("./testdata").
+		AddStruct(&defaults, false).
+		AddJsonFile("test1.json", false)
+
+	v, ok := b.provider[0].(*providers.StructProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no StructProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("a")
+		assertVal(t, v, err, "test3")
+	}
+	{
+		v, err := sec.GetInt("b:b")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := sec.GetInt("b:x")
+		assertVal(t, v, err, 20)
+	}
+	{
+		v, err := sec.GetInt("Port")
+		assertVal(t, v, err, 8080)
+	}
+	{
+		_, err := sec.GetValue("Ignored")
+		if !errors.Is(err, ErrNil) {
+			t.Error("ignored field was not skipped")
+		}
+	}
+
+	if _, err := NewBuilder().AddStruct(nil, true).Build(); err != nil {
+		t.Errorf("optional nil struct errored: %s", err.Error())
+	}
+	if _, err := NewBuilder().AddStruct(1, false).Build(); !errors.Is(err, providers.ErrNoStruct) {
+		t.Errorf("non-struct value did not return ErrNoStruct: %v", err)
+	}
+}
+
+func TestAddRedisHash(t *testing.T) {
+	client := &mockRedisClient{
+		hashes: map[string]map[string]string{
+			"config": {
+				"a":       "redis",
+				"db:host": "localhost",
+				"db:port": "6379",
+			},
+		},
+	}
+
+	b := NewBuilder().
+		AddRedisHash(client, "config", false)
+
+	v, ok := b.provider[0].(*providers.RedisHashProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no RedisHashProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("a")
+		assertVal(t, v, err, "redis")
+	}
+	{
+		v, err := sec.GetString("db:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := sec.GetValue("db:port")
+		assertVal(t, v, err, "6379")
+	}
+
+	sec, err = NewBuilder().
+		AddProvider(providers.NewRedisHashProvider(client, "config", false).SetInferTypes(true)).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := sec.GetValue("db:port")
+		assertVal(t, v, err, 6379)
+	}
+
+	client.err = errors.New("connection refused")
+	if _, err = NewBuilder().AddRedisHash(client, "config", true).Build(); err != nil {
+		t.Errorf("optional failing redis source errored: %s", err.Error())
+	}
+	if _, err = NewBuilder().AddRedisHash(client, "config", false).Build(); !errors.Is(err, client.err) {
+		t.Errorf("failing redis source did not return error: %v", err)
+	}
+}
+
+func TestAddSSM(t *testing.T) {
+	client := &mockSSMClient{
+		pages: [][]providers.SSMParameter{
+			{
+				{Name: "/app/prod/name", Value: "ssm"},
+				{Name: "/app/prod/db/host", Value: "localhost"},
+			},
+			{
+				{Name: "/app/prod/db/password", Value: "secret", Secure: true},
+			},
+		},
+	}
+
+	b := NewBuilder().
+		AddSSM(client, "/app/prod", false, true)
+
+	p, ok := b.provider[0].(*providers.SSMProvider)
+	if !ok || p == nil {
+		t.Fatal("added provider is no SSMProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	if !client.decrypt || !client.recursive {
+		t.Error("parameters were not requested recursively and decrypted")
+	}
+
+	{
+		v, err := sec.GetString("name")
+		assertVal(t, v, err, "ssm")
+	}
+	{
+		v, err := sec.GetString("db:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := sec.GetString("db:password")
+		assertVal(t, v, err, "secret")
+	}
+	assertSlice(t, p.SecretKeys(), nil, []string{"db:password"})
+
+	client.err = errors.New("access denied")
+	if _, err = NewBuilder().AddSSM(client, "/app/prod", true, true).Build(); err != nil {
+		t.Errorf("optional failing ssm source errored: %s", err.Error())
+	}
+	if _, err = NewBuilder().AddSSM(client, "/app/prod", false, true).Build(); !errors.Is(err, client.err) {
+		t.Errorf("failing ssm source did not return error: %v", err)
+	}
+}
+
+func TestAddIniFile(t *testing.T) {
+	b := NewBuilder().
+		SetBasePath("./testdata").
+		AddIniFile("test4.ini", false)
+
+	v, ok := b.provider[0].(*providers.IniProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no IniProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("name")
+		assertVal(t, v, err, "legacy")
+	}
+
+	db := sec.GetSection("database")
+	if db.IsNil() {
+		t.Fatal("database section was nil")
+	}
+	{
+		v, err := db.GetString("host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := db.GetInt("port")
+		assertVal(t, v, err, 5432)
+	}
+
+	server := sec.GetSection("server")
+	if server.IsNil() {
+		t.Fatal("server section was nil")
+	}
+	{
+		v, err := server.GetString("address")
+		assertVal(t, v, err, "0.0.0.0")
+	}
+	{
+		v, err := server.GetInt("port")
+		assertVal(t, v, err, 8080)
+	}
+
+	if _, err = NewBuilder().AddIniFile("none.ini", true).Build(); err != nil {
+		t.Errorf("optional missing file errored: %s", err.Error())
+	}
+}
+
+func TestAddPropertiesFile(t *testing.T) {
+	b := NewBuilder().
+		SetBasePath("./testdata").
+		AddPropertiesFile("test5.properties", false)
+
+	v, ok := b.provider[0].(*providers.PropertiesProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no PropertiesProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("app:name")
+		assertVal(t, v, err, "jvm-service")
+	}
+	{
+		v, err := sec.GetInt("db:pool:size")
+		assertVal(t, v, err, 10)
+	}
+	{
+		v, err := sec.GetSection("db").GetString("url")
+		assertVal(t, v, err, "jdbc:postgresql://localhost/app")
+	}
+	{
+		v, err := sec.GetString("greeting")
+		assertVal(t, v, err, "hello world")
+	}
+	{
+		v, err := sec.GetString("key with spaces")
+		assertVal(t, v, err, "escaped")
+	}
+}
+
+func TestAddXmlFile(t *testing.T) {
+	b := NewBuilder().
+		SetBasePath("./testdata").
+		AddXmlFile("test6.xml", false)
+
+	v, ok := b.provider[0].(*providers.XmlProvider)
+	if !ok || v == nil {
+		t.Error("added provider is no XmlProvider")
+	}
+
+	sec, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := sec.GetString("name")
+		assertVal(t, v, err, "upstream")
+	}
+	{
+		v, err := sec.GetString("server:@host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := sec.GetInt("server:@port")
+		assertVal(t, v, err, 8080)
+	}
+	{
+		v, err := sec.GetBool("server:tls:@enable")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := sec.GetString("label:#text")
+		assertVal(t, v, err, "Hello")
+	}
+	{
+		v, err := sec.GetValue("endpoint")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		arr, ok := v.([]interface{})
+		if !ok || len(arr) != 3 {
+			t.Fatalf("repeated elements (%+v) were not an array of 3", v)
+		}
+		assert(t, arr[0], "/a")
+		assert(t, arr[2], "/c")
+	}
+}
+
+func TestGetSectionsSlice(t *testing.T) {
+	c, err := NewBuilder().
+		AddYamlFile("testdata/servers.yaml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	servers, err := c.GetSectionsSlice("servers")
+	if err != nil {
+		t.Fatalf("recovering returned error: %s", err.Error())
+	}
+	if len(servers) != 2 {
+		t.Fatalf("recovered %d sections instead of 2", len(servers))
+	}
+	{
+		v, err := servers[0].GetString("host")
+		assertVal(t, v, err, "a")
+	}
+	{
+		v, err := servers[1].GetInt("port")
+		assertVal(t, v, err, 8080)
+	}
+
+	{
+		_, err := c.GetSectionsSlice("mixed")
+		var kErr *KeyError
+		if !errors.Is(err, ErrInvalidType) || !errors.As(err, &kErr) || kErr.Key != "mixed:1" {
+			t.Errorf("scalar element did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := c.GetSectionsSlice("servers:0")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("section value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := c.GetSectionsSlice("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+
+	// elements keep the options and the path of
+	// the config
+	rec := &mockAccessRecorder{}
+	c, err = NewBuilder().
+		AddYamlFile("testdata/servers.yaml", false).
+		AddMap(NewConfigMap().Set("_defaults:servers", []interface{}{
+			map[string]interface{}{"user": "admin"},
+		})).
+		WithInlineDefaultsSection("_defaults").
+		WithCaseFoldFallback().
+		WithAccessRecorder(rec).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	servers, err = c.GetSectionsSlice("servers")
+	if err != nil {
+		t.Fatalf("recovering returned error: %s", err.Error())
+	}
+	{
+		v, err := servers[0].GetString("HOST")
+		assertVal(t, v, err, "a")
+	}
+	{
+		v, err := servers[0].GetString("user")
+		assertVal(t, v, err, "admin")
+	}
+	if !reflect.DeepEqual(rec.records[len(rec.records)-2:], []accessRecord{
+		{"servers:0:HOST", false},
+		{"servers:0:user", false},
+	}) {
+		t.Errorf("records (%+v) were not like expected", rec.records)
+	}
+}
+
+func TestDisableArrayIndexing(t *testing.T) {
+	newBuilder := func() *Builder {
+		return NewBuilder().
+			AddProvider(&mockProvider{m: map[string]interface{}{
+				"map":  map[string]interface{}{"0": "a", "1": "b"},
+				"list": []interface{}{"x", map[string]interface{}{"v": 1}},
+			}})
+	}
+
+	c, err := newBuilder().Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("map:1")
+		assertVal(t, v, err, "b")
+	}
+	{
+		v, err := c.GetString("list:0")
+		assertVal(t, v, err, "x")
+	}
+	{
+		v, err := c.GetInt("list:1:v")
+		assertVal(t, v, err, 1)
+	}
+
+	c, err = newBuilder().DisableArrayIndexing().Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("map:1")
+		assertVal(t, v, err, "b")
+	}
+	for _, key := range []string{"list:0", "list:1:v"} {
+		_, err := c.GetValue(key)
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("recovering %s did not return ErrNil: %v", key, err)
+		}
+	}
+	if !c.GetSection("list").IsNil() {
+		t.Error("slice value was returned as section")
+	}
+}
+
+func TestAddScalarFile(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddScalarFile("limits:max", "scalar.txt", false).
+		AddScalarFile("missing", "missing.txt", true).
+		AddProvider(providers.NewScalarFileProvider("testdata/scalar.txt", "typed", false).
+			SetInferTypes(true)).
+		AddProvider(providers.NewScalarFileProvider("testdata/scalar.json", "greeting", false).
+			SetInferTypes(true)).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetValue("limits:max")
+		assertVal(t, v, err, "42")
+	}
+	{
+		v, err := c.GetInt("limits:max")
+		assertVal(t, v, err, 42)
+	}
+	{
+		v, err := c.GetValue("typed")
+		assertVal(t, v, err, 42)
+	}
+	{
+		v, err := c.GetValue("greeting")
+		assertVal(t, v, err, "hello")
+	}
+
+	_, err = NewBuilder().
+		AddScalarFile("missing", "testdata/missing.txt", false).
+		Build()
+	if err == nil {
+		t.Error("missing file did not return an error")
+	}
+}
+
+func TestAddTomlFile(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddTomlFile("test8.toml", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("title")
+		assertVal(t, v, err, "toml")
+	}
+	{
+		v, err := c.GetTime("release:published")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		if !v.Equal(time.Date(2020, 5, 27, 7, 32, 0, 0, time.UTC)) {
+			t.Errorf("recovered time (%s) was not like expected", v)
+		}
+	}
+	{
+		v, err := c.GetTime("release:local")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		if v.Year() != 2020 || v.Month() != 5 || v.Day() != 27 {
+			t.Errorf("recovered date (%s) was not like expected", v)
+		}
+	}
+	{
+		v, err := c.GetTime("release:label")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		if !v.Equal(time.Date(2020, 5, 27, 5, 32, 0, 0, time.UTC)) {
+			t.Errorf("recovered time (%s) was not like expected", v)
+		}
+	}
+	{
+		v, err := c.GetTimeWithLayout("title", "2006")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("recovering returned %s instead of ErrInvalidType: %v", v, err)
+		}
+	}
+	{
+		v, err := c.GetString("servers:1:host")
+		assertVal(t, v, err, "b")
+	}
+}
+
+func TestAddStdin(t *testing.T) {
+	defer func(r *providers.OnceReader) { stdin = r }(stdin)
+
+	stdin = providers.NewOnceReader(strings.NewReader(`{"db": {"host": "stdin-host"}}`))
+	b := NewBuilder().
+		AddMap(NewConfigMap().Set("db:host", "localhost").Set("db:port", 5432)).
+		AddStdin("json", false)
+	c, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "stdin-host")
+	}
+	{
+		v, err := c.GetInt("db:port")
+		assertVal(t, v, err, 5432)
+	}
+
+	// stdin is not read again on reload
+	if err := c.ReloadSource("stdin"); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "stdin-host")
+	}
+
+	// other builders share the read data
+	for _, ob := range []*Builder{
+		NewBuilder().AddStdin("json", false),
+		b.Clone().AddStdin("json", false),
+	} {
+		c, err := ob.Build()
+		if err != nil {
+			t.Fatalf("build of other builder failed: %s", err.Error())
+		}
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "stdin-host")
+	}
+
+	stdin = providers.NewOnceReader(strings.NewReader(""))
+	_, err = NewBuilder().
+		AddStdin("json", false).
+		Build()
+	if !errors.Is(err, providers.ErrEmptyStdin) {
+		t.Errorf("error (%v) did not match ErrEmptyStdin", err)
+	}
+
+	_, err = NewBuilder().
+		AddStdin("yaml", true).
+		Build()
+	if err != nil {
+		t.Errorf("build with optional empty stdin failed: %s", err.Error())
+	}
+
+	stdin = providers.NewOnceReader(strings.NewReader(" \n\t\n"))
+	_, err = NewBuilder().
+		AddStdin("json", true).
+		Build()
+	if err != nil {
+		t.Errorf("build with optional blank stdin failed: %s", err.Error())
+	}
+	_, err = NewBuilder().
+		AddStdin("json", false).
+		Build()
+	if !errors.Is(err, providers.ErrEmptyStdin) {
+		t.Errorf("error (%v) did not match ErrEmptyStdin", err)
+	}
+}
+
+func TestAddRaw(t *testing.T) {
+	c, err := NewBuilder().
+		AddRaw("yaml", "db:\n  host: localhost\n  port: 5432\n", false).
+		AddRaw("json", `{"db": {"port": 6432}, "name": "app"}`, false).
+		AddRaw("toml", "[cache]\nttl = 30\n", false).
+		AddRaw("env", "DB__USER=admin\n", false).
+		AddRaw("json", "", true).
+		AddRaw("yaml", " \n", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetString("db:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := c.GetInt("db:port")
+		assertVal(t, v, err, 6432)
+	}
+	{
+		v, err := c.GetString("name")
+		assertVal(t, v, err, "app")
+	}
+	{
+		v, err := c.GetInt("cache:ttl")
+		assertVal(t, v, err, 30)
+	}
+	{
+		v, err := c.GetString("DB:USER")
+		assertVal(t, v, err, "admin")
+	}
+
+	_, err = NewBuilder().AddRaw("json", `{invalid`, true).Build()
+	if err == nil {
+		t.Error("invalid optional data did not return an error")
+	}
+
+	_, err = NewBuilder().AddRaw("json", `{invalid`, false).Build()
+	if err == nil {
+		t.Error("invalid required data did not return an error")
+	}
+
+	for _, optional := range []bool{false, true} {
+		_, err = NewBuilder().AddRaw("hcl", `a = 1`, optional).Build()
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("unsupported format did not return ErrUnsupportedFormat: %v", err)
+		}
+		_, err = NewBuilder().AddStdin("hcl", optional).Build()
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("unsupported stdin format did not return ErrUnsupportedFormat: %v", err)
+		}
+	}
+}
+
+func TestAddDotEnvFiles(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata/dotenvflow").
+		AddDotEnvFiles([]string{".env", ".env.local", ".env.production"}, true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if len(c.Sources()) != 3 {
+		t.Errorf("sources (%+v) were not like expected", c.Sources())
+	}
+
+	{
+		v, err := c.GetString("APP_NAME")
+		assertVal(t, v, err, "base")
+	}
+	{
+		v, err := c.GetString("LOG_LEVEL")
+		assertVal(t, v, err, "debug")
+	}
+	{
+		v, err := c.GetString("DB:HOST")
+		assertVal(t, v, err, "localhost")
+	}
+
+	_, err = NewBuilder().
+		SetBasePath("testdata/dotenvflow").
+		AddDotEnvFiles([]string{".env", ".env.production"}, false).
+		Build()
+	if err == nil {
+		t.Error("build of missing env file did not fail")
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddFile("test1.json", false).
+		AddFile("test3.yaml", false).
+		AddFile("test8.toml", false).
+		AddFile("test4.ini", false).
+		AddFile("test5.properties", false).
+		AddFile("test6.xml", false).
+		AddFile("test9.env", false).
+		AddFile("does_not_exist.conf", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if len(c.Sources()) != 7 {
+		t.Errorf("sources (%+v) were not like expected", c.Sources())
+	}
+
+	{
+		v, err := c.GetString("a")
+		assertVal(t, v, err, "test3")
+	}
+	{
+		v, err := c.GetInt("y:e")
+		assertVal(t, v, err, 1123)
+	}
+	{
+		v, err := c.GetString("title")
+		assertVal(t, v, err, "toml")
+	}
+	{
+		v, err := c.GetString("database:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := c.GetString("app:name")
+		assertVal(t, v, err, "jvm-service")
+	}
+	{
+		v, err := c.GetString("server:@host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := c.GetString("APP_NAME")
+		assertVal(t, v, err, "dotenv")
+	}
+	{
+		v, err := c.GetString("DB:HOST")
+		assertVal(t, v, err, "db.local")
+	}
+	{
+		v, err := c.GetString("DB:USER")
+		assertVal(t, v, err, "admin # not a comment")
+	}
+	{
+		v, err := c.GetString("DB:PORT")
+		assertVal(t, v, err, "5432")
+	}
+	{
+		v, err := c.GetString("EMPTY")
+		assertVal(t, v, err, "")
+	}
+
+	_, err = NewBuilder().
+		SetBasePath("testdata").
+		AddFile("test1.conf", false).
+		Build()
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("unknown extension did not return ErrUnsupportedFormat: %v", err)
+	}
+}
+
+func TestAddCsvFile(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddCsvFile("test7.csv", "data:regions", false).
+		AddCsvFile("missing.csv", "missing", true).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	sec := c.GetSection("data:regions:1")
+	if sec.IsNil() {
+		t.Fatal("recovered row section was nil")
+	}
+	{
+		v, err := sec.GetString("code")
+		assertVal(t, v, err, "us")
+	}
+	{
+		v, err := sec.GetString("name")
+		assertVal(t, v, err, "United States")
+	}
+	{
+		v, err := c.GetInt("data:regions:0:replicas")
+		assertVal(t, v, err, 3)
+	}
+	{
+		v, err := c.GetValue("data:regions")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		if arr, ok := v.([]interface{}); !ok || len(arr) != 2 {
+			t.Errorf("rows (%+v) were not an array of 2", v)
+		}
+	}
+	{
+		_, err := c.GetValue("data:regions:2:code")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func BenchmarkBuildJsonFile(b *testing.B) {
+	fileName := writeLargeJsonFile(b)
+	defer os.Remove(fileName)
+
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c, _ := NewBuilder().AddJsonFile(fileName, false).Build()
+			c.GetString("section500:key5")
+		}
+	})
+
+	b.Run("lazy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c, _ := NewBuilder().AddJsonFileLazy(fileName, false).Build()
+			c.GetString("section500:key5")
+		}
+	})
+}
+
+// --------------------------------------------------------------------------
 // --- HELPERS
 
+// writeLargeJsonFile writes a JSON file with 1000
+// sections each containing 100 keys to a temporary
+// file and returns its name.
+func writeLargeJsonFile(b *testing.B) string {
+	m := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		sec := make(map[string]interface{})
+		for j := 0; j < 100; j++ {
+			sec[fmt.Sprintf("key%d", j)] = fmt.Sprintf("value%d-%d", i, j)
+		}
+		m[fmt.Sprintf("section%d", i)] = sec
+	}
+
+	f, err := ioutil.TempFile("", "configoration-*.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	if err = json.NewEncoder(f).Encode(m); err != nil {
+		b.Fatal(err)
+	}
+
+	return f.Name()
+}
+
+type mockRedisClient struct {
+	hashes map[string]map[string]string
+	err    error
+}
+
+func (c *mockRedisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.hashes[key], nil
+}
+
+type mockSSMClient struct {
+	pages     [][]providers.SSMParameter
+	recursive bool
+	decrypt   bool
+	err       error
+}
+
+func (c *mockSSMClient) GetParametersByPath(ctx context.Context, path string,
+	recursive, decrypt bool, nextToken string) ([]providers.SSMParameter, string, error) {
+
+	if c.err != nil {
+		return nil, "", c.err
+	}
+	c.recursive = recursive
+	c.decrypt = decrypt
+
+	i := 0
+	if nextToken != "" {
+		i, _ = strconv.Atoi(nextToken)
+	}
+	var next string
+	if i+1 < len(c.pages) {
+		next = strconv.Itoa(i + 1)
+	}
+	return c.pages[i], next, nil
+}
+
 func assertVal(t *testing.T, val interface{}, err error, expected interface{}) {
 	if err != nil {
 		t.Errorf("get value errored: %s", err.Error())
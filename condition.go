@@ -0,0 +1,41 @@
+package configoration
+
+// conditionalProvider wraps a Provider which is
+// only read if cond returns true.
+type conditionalProvider struct {
+	Provider
+	cond func() bool
+}
+
+func (p *conditionalProvider) GetMap() (map[string]interface{}, error) {
+	if !p.cond() {
+		return nil, nil
+	}
+	return p.Provider.GetMap()
+}
+
+func (p *conditionalProvider) Name() string {
+	return providerName(p.Provider)
+}
+
+// Optional returns true if the wrapped provider
+// is optional or currently excluded, so that
+// excluded sources are not reported as empty.
+func (p *conditionalProvider) Optional() bool {
+	return isOptional(p.Provider) || !p.cond()
+}
+
+// FileName returns the file name of the wrapped
+// provider while it is included. While it is
+// excluded, the name is empty, so that the
+// source is read again once it is included.
+func (p *conditionalProvider) FileName() string {
+	if fp, ok := p.Provider.(FileProvider); ok && p.cond() {
+		return fp.FileName()
+	}
+	return ""
+}
+
+func (p *conditionalProvider) unwrap() Provider {
+	return p.Provider
+}
@@ -0,0 +1,406 @@
+package configoration
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/zekroTJA/configoration/providers"
+)
+
+// Config is the root Section built by the
+// Builder which additionally provides functions
+// to manage the lifecycle of the config.
+type Config interface {
+	Section
+
+	// Close stops all background goroutines of
+	// the config, like the periodic refresh, and
+	// closes all providers implementing io.Closer.
+	// Close blocks until all goroutines have exited.
+	//
+	// Providers passed to the Builder which
+	// implement io.Closer are owned by the config,
+	// also if they are wrapped by If or
+	// AddProviderTransform. Clients passed to
+	// providers, like to AddRedisHash or AddSSM,
+	// are not closed.
+	//
+	// Calling Close on a config without background
+	// tasks or owned resources is a no-op. Only
+	// the first call closes the providers; the
+	// first error returned by a provider is
+	// returned.
+	Close() error
+
+	// Sources returns the names of all sources
+	// merged into the config in the order they
+	// were added to the Builder.
+	Sources() []string
+
+	// LoadedAt returns the time the config was
+	// last built or reloaded.
+	LoadedAt() time.Time
+
+	// AllSettings returns a deep copy of all
+	// merged config values as nested maps.
+	AllSettings() map[string]interface{}
+
+	// FlatMap returns all values of the config
+	// as strings keyed by their full path joined
+	// by the Delimiter, like "db:hosts:0" for
+	// the first element of the slice "db:hosts".
+	// Empty sections and slices are omitted.
+	FlatMap() map[string]string
+
+	// Equal returns true if other contains the
+	// same keys and values as the config.
+	//
+	// Numbers of different types are equal if
+	// they represent the same value, so that an
+	// int 8080 read from YAML equals a float64
+	// 8080 read from JSON. Other values must be
+	// of the same type.
+	Equal(other Config) bool
+
+	// ReloadSource reads the source with the
+	// passed name, as returned by Sources, again
+	// and merges its values with the cached
+	// values of all other sources. If no source
+	// has the passed name, an error matching
+	// ErrUnknownSource is returned.
+	ReloadSource(name string) error
+
+	// GetFromSource returns the value of the
+	// passed key as read from the source with
+	// the passed name, as returned by Sources,
+	// before it was merged with the values of
+	// other sources. Reads are not passed to the
+	// AccessRecorder and inline defaults are not
+	// applied. If no source has the passed name,
+	// an error matching ErrUnknownSource is
+	// returned, and if multiple sources have the
+	// name, like a file added twice, an error
+	// matching ErrAmbiguousSource.
+	GetFromSource(source string, key string) (interface{}, error)
+
+	// Export encodes all values of the config in
+	// the passed format, which is json or yaml.
+	// The keys of each section are sorted, unless
+	// the key order of the sources is preserved
+	// with Builder.SetPreserveKeyOrder. If the
+	// format is not supported, an error
+	// matching ErrUnsupportedFormat is returned.
+	Export(format string) ([]byte, error)
+}
+
+// config is the default implementation of
+// the Config interface.
+type config struct {
+	*section
+
+	builder  *Builder
+	sources  []string
+	loadedAt time.Time
+
+	// reloadMtx serializes reloads, which read
+	// and replace the cached source states.
+	reloadMtx sync.Mutex
+	states    []sourceState
+
+	// order is the key order of the sources used
+	// by Export.
+	order providers.KeyOrder
+
+	closeOnce sync.Once
+	closeErr  error
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// newConfig returns a new config instance with
+// the passed map as root section which is
+// rebuilt using the passed builder.
+func newConfig(b *Builder, m ConfigMap) *config {
+	sources := make([]string, len(b.provider))
+	for i, p := range b.provider {
+		sources[i] = providerName(p)
+	}
+
+	c := &config{
+		section: &section{
+			mtx: &sync.Mutex{},
+			m:   m,
+			opts: &sectionOptions{
+				keyNormalizer:   b.keyNormalizer,
+				typeErrHandler:  b.typeErrHandler,
+				noArrayIndexing: b.noArrayIndexing,
+				lazyInterpolate: b.lazyInterpolate,
+				overflowPolicy:  b.overflowPolicy,
+				readOnly:        b.readOnly,
+				caseFold:        b.caseFold,
+				accessRecorder:  b.accessRecorder,
+				strictTypes:     b.strictTypes,
+			},
+		},
+		builder:  b,
+		sources:  sources,
+		loadedAt: time.Now(),
+		stop:     make(chan struct{}),
+	}
+	c.root = c.section
+	if b.inlineDefaults != "" {
+		c.opts.inlineDefaults = b.normalizePath(b.inlineDefaults)
+	}
+
+	if b.caseFold {
+		c.folded = foldKeys(m)
+	}
+	if b.readOnly {
+		c.resolveChildren()
+	}
+
+	return c
+}
+
+func (c *config) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.wg.Wait()
+
+		for _, p := range c.builder.provider {
+			closer, ok := unwrapProvider(p).(io.Closer)
+			if !ok {
+				continue
+			}
+			if err := closer.Close(); err != nil && c.closeErr == nil {
+				c.closeErr = err
+			}
+		}
+	})
+	c.wg.Wait()
+	return c.closeErr
+}
+
+func (c *config) Sources() []string {
+	return append([]string(nil), c.sources...)
+}
+
+func (c *config) LoadedAt() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.loadedAt
+}
+
+func (c *config) AllSettings() map[string]interface{} {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return plainCopy(c.m).(map[string]interface{})
+}
+
+func (c *config) FlatMap() map[string]string {
+	res := make(map[string]string)
+	flatten(res, "", c.AllSettings())
+	return res
+}
+
+func (c *config) Equal(other Config) bool {
+	if other == nil {
+		return false
+	}
+	return valuesEqual(c.AllSettings(), other.AllSettings())
+}
+
+// startRefresh starts a goroutine which reloads
+// the config every interval until Close is
+// called.
+func (c *config) startRefresh(interval time.Duration) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.reloadAndReport()
+			}
+		}
+	}()
+}
+
+// startWatch starts a goroutine which reloads
+// the config every interval until ctx is done
+// or Close is called. Each time a reload changes
+// the config values, a notification is sent on
+// the returned channel, which is closed when the
+// goroutine exits.
+func (c *config) startWatch(ctx context.Context, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(changed)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				if !c.reloadAndReport() {
+					continue
+				}
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed
+}
+
+// reloadAndReport reloads the config and passes
+// a reload error to the reload error handler,
+// if set. It returns true if the config values
+// have changed.
+func (c *config) reloadAndReport() bool {
+	changed, err := c.reload()
+	if err != nil && c.builder.reloadErrHandler != nil {
+		c.builder.reloadErrHandler(err)
+	}
+	return changed
+}
+
+// reload rebuilds the config map from all
+// providers and swaps it with the current one.
+// File providers whose file did not change are
+// not read again. If the rebuild fails, the
+// current map is kept and the error is
+// returned. changed is true if the new map
+// differs from the current one.
+func (c *config) reload() (changed bool, err error) {
+	return c.reloadSources(func(i int, st sourceState) bool {
+		return st.changedSince(c.states[i])
+	})
+}
+
+func (c *config) ReloadSource(name string) error {
+	found := false
+	for _, n := range c.sources {
+		found = found || n == name
+	}
+	if !found {
+		return &SourceError{Source: name, Err: ErrUnknownSource}
+	}
+
+	_, err := c.reloadSources(func(i int, _ sourceState) bool {
+		return c.sources[i] == name
+	})
+	return err
+}
+
+func (c *config) GetFromSource(source string, key string) (interface{}, error) {
+	c.reloadMtx.Lock()
+	var raw map[string]interface{}
+	found := 0
+	for i, n := range c.sources {
+		if n == source {
+			raw = c.states[i].m
+			found++
+		}
+	}
+	c.reloadMtx.Unlock()
+
+	switch {
+	case found == 0:
+		return nil, &SourceError{Source: source, Err: ErrUnknownSource}
+	case found > 1:
+		return nil, &SourceError{Source: source, Err: ErrAmbiguousSource}
+	}
+
+	// the values are copied, so that lazy
+	// sections are decoded into maps which are
+	// not shared with the merged config
+	m, _ := copyValue(ConfigMap(raw)).(ConfigMap)
+	if m == nil {
+		m = make(ConfigMap)
+	}
+	if b := c.builder; b.rootKey != "" {
+		root, ok := m.sectionAt(b.normalizePath(b.rootKey))
+		if !ok {
+			return nil, newKeyError(key, ErrNil)
+		}
+		m = root
+	}
+
+	// reads of single sources are not recorded
+	// and have no inline defaults
+	opts := *c.opts
+	opts.accessRecorder = nil
+	opts.inlineDefaults = nil
+
+	s := &section{
+		mtx:  &sync.Mutex{},
+		m:    m,
+		opts: &opts,
+	}
+	return s.GetValue(key)
+}
+
+// reloadSources reads all providers again for
+// which reread returns true and merges their
+// values with the cached values of all other
+// providers like reload.
+func (c *config) reloadSources(reread func(i int, st sourceState) bool) (changed bool, err error) {
+	if c.readOnly() {
+		return false, ErrReadOnly
+	}
+
+	c.reloadMtx.Lock()
+	defer c.reloadMtx.Unlock()
+
+	start := time.Now()
+	states, err := c.builder.loadSources(c.states, reread)
+	var m ConfigMap
+	if err == nil {
+		m, err = c.builder.mergeSources(states)
+	}
+	if err != nil {
+		c.builder.emit(Event{Type: EventReloaded, Duration: time.Since(start), Err: err})
+		return false, err
+	}
+	c.builder.emit(Event{Type: EventReloaded, Duration: time.Since(start)})
+	c.states = states
+	order := c.builder.mergeKeyOrder(states)
+
+	var changes []Change
+	c.mtx.Lock()
+	c.order = order
+	changed = !valuesEqual(c.m, m)
+	if changed && c.builder.reloadHandler != nil {
+		changes = diffValues(c.m, m)
+	}
+	c.m = m
+	c.invalidateCaches()
+	c.loadedAt = time.Now()
+	c.mtx.Unlock()
+
+	if len(changes) > 0 {
+		c.builder.reloadHandler(changes)
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,836 @@
+package configoration
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRefreshInterval(t *testing.T) {
+	p := &mockProvider{m: map[string]interface{}{"v": 1}}
+
+	c, err := NewBuilder().
+		AddProvider(p).
+		WithRefreshInterval(5 * time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	defer c.Close()
+
+	{
+		v, err := c.GetInt("v")
+		assertVal(t, v, err, 1)
+	}
+
+	p.set(map[string]interface{}{"v": 2}, nil)
+	if !waitFor(func() bool { return c.GetIntOrDef("v", 0) == 2 }) {
+		t.Error("config was not refreshed")
+	}
+
+	p.set(nil, errors.New("source unavailable"))
+	time.Sleep(20 * time.Millisecond)
+	{
+		v, err := c.GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+}
+
+func TestReloadInvalidatesSectionCache(t *testing.T) {
+	p := &mockProvider{m: map[string]interface{}{
+		"a": map[string]interface{}{"v": 1},
+	}}
+
+	c, err := NewBuilder().
+		AddProvider(p).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetSection("a").GetInt("v")
+		assertVal(t, v, err, 1)
+	}
+
+	p.set(map[string]interface{}{
+		"a": map[string]interface{}{"v": 2},
+	}, nil)
+	if _, err = c.(*config).reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+
+	if len(c.(*config).children) != 0 {
+		t.Error("section cache was not cleared on reload")
+	}
+	{
+		v, err := c.GetSection("a").GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+	{
+		v, err := c.GetInt("a:v")
+		assertVal(t, v, err, 2)
+	}
+}
+
+func TestReloadInvalidatesCaches(t *testing.T) {
+	p := &mockProvider{m: map[string]interface{}{
+		"a":    map[string]interface{}{"b": map[string]interface{}{"v": 1}},
+		"list": []interface{}{map[string]interface{}{"v": 1}},
+	}}
+
+	c, err := NewBuilder().
+		AddProvider(p).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	cfg := c.(*config)
+
+	ck := CompileKey("a:b:v")
+	{
+		v, err := c.GetIntCompiled(ck)
+		assertVal(t, v, err, 1)
+		v, err = c.GetInt("list:0:v")
+		assertVal(t, v, err, 1)
+	}
+	if n := cfg.cachedSections(); n != 4 {
+		t.Errorf("cached sections (%d) were not like expected (4)", n)
+	}
+
+	p.set(map[string]interface{}{
+		"a":    map[string]interface{}{"b": map[string]interface{}{"v": 2}},
+		"list": []interface{}{map[string]interface{}{"v": 2}},
+	}, nil)
+	if _, err = cfg.reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+
+	if n := cfg.cachedSections(); n != 0 {
+		t.Errorf("%d cached sections were not cleared on reload", n)
+	}
+	{
+		v, err := c.GetIntCompiled(ck)
+		assertVal(t, v, err, 2)
+		v, err = c.GetInt("list:0:v")
+		assertVal(t, v, err, 2)
+		v, err = c.WithPrefix("a:b").GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+}
+
+func TestCloseWithoutRefresh(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("test1.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if err = c.Close(); err != nil {
+		t.Errorf("close failed: %s", err.Error())
+	}
+	if err = c.Close(); err != nil {
+		t.Errorf("second close failed: %s", err.Error())
+	}
+}
+
+func TestCloseStopsRefresh(t *testing.T) {
+	p := &mockProvider{m: map[string]interface{}{"v": 1}}
+
+	c, err := NewBuilder().
+		AddProvider(p).
+		WithRefreshInterval(time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if !waitFor(func() bool { return p.getCalls() > 2 }) {
+		t.Fatal("config was not refreshed")
+	}
+
+	if err = c.Close(); err != nil {
+		t.Errorf("close failed: %s", err.Error())
+	}
+
+	calls := p.getCalls()
+	time.Sleep(20 * time.Millisecond)
+	if p.getCalls() != calls {
+		t.Error("refresh goroutine was still running after close")
+	}
+}
+
+func TestCloseClosesProviders(t *testing.T) {
+	closeErr := errors.New("close failed")
+	p1 := &mockClosingProvider{}
+	p2 := &mockClosingProvider{closeErr: closeErr}
+
+	c, err := NewBuilder().
+		AddProvider(p1).
+		AddProvider(&mockProvider{}).
+		AddProvider(p2).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if err = c.Close(); err != closeErr {
+		t.Errorf("close error (%+v) was not like expected (%+v)", err, closeErr)
+	}
+	if err = c.Close(); err != closeErr {
+		t.Errorf("second close error (%+v) was not like expected (%+v)", err, closeErr)
+	}
+
+	if p1.closed != 1 || p2.closed != 1 {
+		t.Errorf("providers were closed %d and %d times instead of once", p1.closed, p2.closed)
+	}
+}
+
+func TestCloseClosesWrappedProviders(t *testing.T) {
+	p1 := &mockClosingProvider{}
+	p2 := &mockClosingProvider{}
+
+	identity := func(m ConfigMap) (ConfigMap, error) {
+		return m, nil
+	}
+
+	c, err := NewBuilder().
+		AddProviderTransform(p1, identity).
+		AddProvider(p2).
+		If(func() bool { return true }).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	if err = c.Close(); err != nil {
+		t.Errorf("close failed: %s", err.Error())
+	}
+
+	if p1.closed != 1 || p2.closed != 1 {
+		t.Errorf("providers were closed %d and %d times instead of once", p1.closed, p2.closed)
+	}
+}
+
+func TestSources(t *testing.T) {
+	before := time.Now()
+
+	c, err := NewBuilder().
+		SetBasePath("./testdata").
+		AddJsonFile("test1.json", false).
+		AddYamlFile("test3.yaml", false).
+		AddEnvironmentVariables("TEST_", false).
+		AddJsonFile("test2.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	expected := []string{
+		"testdata/test1.json",
+		"testdata/test3.yaml",
+		"env:TEST_",
+		"testdata/test2.json",
+	}
+	sources := c.Sources()
+	if len(sources) != len(expected) {
+		t.Fatalf("sources (%+v) were not like expected (%+v)", sources, expected)
+	}
+	for i, s := range sources {
+		assert(t, s, expected[i])
+	}
+
+	if c.LoadedAt().Before(before) || c.LoadedAt().After(time.Now()) {
+		t.Errorf("loaded at time (%s) was not in the expected range", c.LoadedAt())
+	}
+}
+
+func TestGetFromSource(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test1.json", false).
+		AddJsonFile("test2.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	v, err := c.GetValue("a")
+	assertVal(t, v, err, "test2")
+
+	v, err = c.GetFromSource("testdata/test1.json", "a")
+	assertVal(t, v, err, "test3")
+
+	v, err = c.GetFromSource("testdata/test2.json", "a")
+	assertVal(t, v, err, "test2")
+
+	v, err = c.GetFromSource("testdata/test1.json", "b:e")
+	assertVal(t, v, err, 3.0)
+
+	_, err = c.GetFromSource("testdata/test2.json", "b:e")
+	if !errors.Is(err, ErrNil) {
+		t.Errorf("error (%v) did not match ErrNil", err)
+	}
+
+	_, err = c.GetFromSource("testdata/test3.yaml", "a")
+	if !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("error (%v) did not match ErrUnknownSource", err)
+	}
+
+	rec := &mockAccessRecorder{}
+	c, err = NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test1.json", false).
+		AddJsonFile("test2.json", false).
+		AddJsonFile("test1.json", false).
+		WithAccessRecorder(rec).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	_, err = c.GetFromSource("testdata/test1.json", "a")
+	if !errors.Is(err, ErrAmbiguousSource) {
+		t.Errorf("error (%v) did not match ErrAmbiguousSource", err)
+	}
+
+	v, err = c.GetFromSource("testdata/test2.json", "a")
+	assertVal(t, v, err, "test2")
+	if len(rec.records) != 0 {
+		t.Errorf("reads of single sources were recorded: %+v", rec.records)
+	}
+	// lazy sections do not contain the values of
+	// later sources
+	fileName := filepath.Join(t.TempDir(), "a.json")
+	writeFile(t, fileName, `{"s": {"a": 1}}`)
+	c, err = NewBuilder().
+		AddJsonFileLazy(fileName, false).
+		AddMap(NewConfigMap().Set("s:b", 2)).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	v, err = c.GetFromSource(fileName, "s:a")
+	assertVal(t, v, err, 1.0)
+	if _, err = c.GetFromSource(fileName, "s:b"); !errors.Is(err, ErrNil) {
+		t.Errorf("error (%v) did not match ErrNil", err)
+	}
+	v, err = c.GetValue("s:b")
+	assertVal(t, v, err, 2)
+}
+
+func TestLoadedAtRefresh(t *testing.T) {
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{}).
+		WithRefreshInterval(time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	defer c.Close()
+
+	builtAt := c.LoadedAt()
+	if !waitFor(func() bool { return c.LoadedAt().After(builtAt) }) {
+		t.Error("loaded at time was not updated on refresh")
+	}
+}
+
+func TestBuildAndWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configoration")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "config.json")
+	if err = ioutil.WriteFile(fileName, []byte(`{"v": 1}`), 0644); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var reloadErrs int32
+	c, changed, err := NewBuilder().
+		AddJsonFile(fileName, false).
+		WithRefreshInterval(5 * time.Millisecond).
+		WithReloadErrorHandler(func(err error) {
+			atomic.AddInt32(&reloadErrs, 1)
+		}).
+		BuildAndWatch(ctx)
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	defer c.Close()
+
+	{
+		v, err := c.GetInt("v")
+		assertVal(t, v, err, 1)
+	}
+
+	if err = ioutil.WriteFile(fileName, []byte(`{"v": 2}`), 0644); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("no change notification was received")
+	}
+	{
+		v, err := c.GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+
+	if err = ioutil.WriteFile(fileName, []byte(`{"v":`), 0644); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+	if !waitFor(func() bool { return atomic.LoadInt32(&reloadErrs) > 0 }) {
+		t.Error("reload error was not reported")
+	}
+	{
+		v, err := c.GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changed:
+		for ok {
+			_, ok = <-changed
+		}
+	case <-time.After(time.Second):
+		t.Error("change channel was not closed after cancel")
+	}
+}
+
+func TestBuildAndWatchBuildError(t *testing.T) {
+	_, changed, err := NewBuilder().
+		AddJsonFile("testdata/missing.json", false).
+		BuildAndWatch(context.Background())
+	if err == nil {
+		t.Error("missing file did not return an error")
+	}
+	if changed != nil {
+		t.Error("change channel was returned on error")
+	}
+}
+
+func TestAllSettings(t *testing.T) {
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{m: map[string]interface{}{
+			"a": 1,
+			"b": map[string]interface{}{"c": "x", "d": []interface{}{1, 2}},
+		}}).
+		AddProvider(&mockProvider{m: map[string]interface{}{
+			"b": map[interface{}]interface{}{"c": "y"},
+			"e": true,
+		}}).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	expected := map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": "y", "d": []interface{}{1, 2}},
+		"e": true,
+	}
+	all := c.AllSettings()
+	if !reflect.DeepEqual(all, expected) {
+		t.Errorf("settings (%+v) were not like expected (%+v)", all, expected)
+	}
+
+	all["b"].(map[string]interface{})["c"] = "z"
+	{
+		v, err := c.GetString("b:c")
+		assertVal(t, v, err, "y")
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	c, err := NewBuilder().
+		AddProvider(&mockProvider{m: map[string]interface{}{
+			"a": 1,
+			"b": map[string]interface{}{
+				"c": "x",
+				"d": []interface{}{1.5, map[string]interface{}{"e": true}},
+			},
+			"empty": map[string]interface{}{},
+			"tags":  []string{"t1", "t2"},
+		}}).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	expected := map[string]string{
+		"a":       "1",
+		"b:c":     "x",
+		"b:d:0":   "1.5",
+		"b:d:1:e": "true",
+		"tags:0":  "t1",
+		"tags:1":  "t2",
+	}
+	flat := c.FlatMap()
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("flat map (%+v) was not like expected (%+v)", flat, expected)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	build := func(m map[string]interface{}) Config {
+		c, err := NewBuilder().
+			AddProvider(&mockProvider{m: m}).
+			Build()
+		if err != nil {
+			t.Fatalf("build failed: %s", err.Error())
+		}
+		return c
+	}
+
+	c1 := build(map[string]interface{}{
+		"a": "x",
+		"b": map[string]interface{}{"port": 8080, "list": []interface{}{1, "y"}},
+	})
+	c2 := build(map[string]interface{}{
+		"b": map[interface{}]interface{}{"list": []interface{}{1.0, "y"}, "port": 8080.0},
+		"a": "x",
+	})
+	c3 := build(map[string]interface{}{
+		"a": "x",
+		"b": map[string]interface{}{"port": 8081, "list": []interface{}{1, "y"}},
+	})
+	c4 := build(map[string]interface{}{
+		"a": "x",
+		"b": map[string]interface{}{"port": "8080", "list": []interface{}{1, "y"}},
+	})
+
+	if !c1.Equal(c1) || !c1.Equal(c2) || !c2.Equal(c1) {
+		t.Error("equal configs were not equal")
+	}
+	if c1.Equal(c3) {
+		t.Error("configs with different values were equal")
+	}
+	if c1.Equal(c4) {
+		t.Error("number and string values were equal")
+	}
+	if c1.Equal(nil) {
+		t.Error("config was equal to nil")
+	}
+	if valuesEqual(8080.5, 8080) || !valuesEqual(uint8(1), int64(1)) || valuesEqual(uint(1), -1) {
+		t.Error("number comparison was not like expected")
+	}
+}
+
+func TestOnReload(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, fileName, `{"db": {"host": "a", "port": 1}, "name": "app"}`)
+
+	var calls [][]Change
+	c, err := NewBuilder().
+		AddJsonFile(fileName, false).
+		OnReload(func(changes []Change) {
+			calls = append(calls, changes)
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	cfg := c.(*config)
+
+	writeFile(t, fileName, `{"db": {"host": "bb", "port": 22}, "name": "app"}`)
+	if _, err = cfg.reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	if len(calls) != 1 {
+		t.Fatalf("handler was called %d times instead of once", len(calls))
+	}
+	if !reflect.DeepEqual(calls[0], []Change{
+		{Key: "db:host", Old: "a", New: "bb"},
+		{Key: "db:port", Old: float64(1), New: float64(22)},
+	}) {
+		t.Errorf("changes (%+v) were not like expected", calls[0])
+	}
+
+	if _, err = cfg.reloadSources(func(int, sourceState) bool { return true }); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	if len(calls) != 1 {
+		t.Error("handler was called for a reload without changes")
+	}
+
+	writeFile(t, fileName, `{"db": {"host": "bb", "port": 22, "user": null}, "name": "app"}`)
+	if _, err = cfg.reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	writeFile(t, fileName, `{"db": {"host": "bb", "port": 22}, "name": "app", "extra": null}`)
+	if _, err = cfg.reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	if len(calls) != 3 {
+		t.Fatalf("handler was called %d times instead of three times", len(calls))
+	}
+	if !reflect.DeepEqual(calls[1], []Change{{Key: "db:user"}}) {
+		t.Errorf("changes (%+v) were not like expected", calls[1])
+	}
+	if !reflect.DeepEqual(calls[2], []Change{{Key: "db:user"}, {Key: "extra"}}) {
+		t.Errorf("changes (%+v) were not like expected", calls[2])
+	}
+}
+
+func TestPartialReload(t *testing.T) {
+	dir := t.TempDir()
+	fileA := filepath.Join(dir, "a.json")
+	fileB := filepath.Join(dir, "b.json")
+	writeFile(t, fileA, `{"a": 1, "shared": "a"}`)
+	writeFile(t, fileB, `{"b": 1, "shared": "b"}`)
+	p := &mockProvider{m: map[string]interface{}{"m": 1}}
+
+	var loaded []string
+	b := NewBuilder().
+		WithLogger(func(event Event) {
+			if event.Type == EventSourceLoaded {
+				loaded = append(loaded, event.Source)
+			}
+		}).
+		AddJsonFile(fileA, false).
+		AddJsonFile(fileB, false).
+		AddProvider(p)
+	c, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	cfg := c.(*config)
+
+	loaded = nil
+	writeFile(t, fileA, `{"a": 22, "shared": "a"}`)
+	if _, err = cfg.reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	assertSlice(t, loaded, nil, []string{fileA, providerName(p)})
+	{
+		v, err := c.GetInt("a")
+		assertVal(t, v, err, 22)
+	}
+	{
+		v, err := c.GetString("shared")
+		assertVal(t, v, err, "b")
+	}
+
+	loaded = nil
+	writeFile(t, fileB, `{"b": 333}`)
+	if err = c.ReloadSource(fileB); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	assertSlice(t, loaded, nil, []string{fileB})
+
+	full, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	if !reflect.DeepEqual(c.AllSettings(), full.AllSettings()) {
+		t.Errorf("partially reloaded settings (%+v) differ from full build (%+v)",
+			c.AllSettings(), full.AllSettings())
+	}
+
+	if err = c.ReloadSource("unknown"); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("unknown source did not return ErrUnknownSource: %v", err)
+	}
+}
+
+func TestPartialReloadLazy(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "a.json")
+	writeFile(t, fileName, `{"s": {"a": 1}}`)
+	p := &mockProvider{m: map[string]interface{}{
+		"s": map[string]interface{}{"b": 2},
+	}}
+
+	c, err := NewBuilder().
+		AddJsonFileLazy(fileName, false).
+		AddProvider(p).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("s:b")
+		assertVal(t, v, err, 2)
+	}
+
+	p.set(map[string]interface{}{"s": map[string]interface{}{"c": 3}}, nil)
+	if _, err = c.(*config).reload(); err != nil {
+		t.Fatalf("reload failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("s:a")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := c.GetInt("s:c")
+		assertVal(t, v, err, 3)
+	}
+	if _, err = c.GetValue("s:b"); !errors.Is(err, ErrNil) {
+		t.Errorf("removed key was still present: %v", err)
+	}
+}
+
+func BenchmarkReload(b *testing.B) {
+	dir := b.TempDir()
+	builder := NewBuilder()
+	var fileName string
+	for i := 0; i < 10; i++ {
+		fileName = filepath.Join(dir, strconv.Itoa(i)+".json")
+		writeFile(b, fileName, `{"a": {"b": [1, 2, 3], "c": "value"}, "d": `+strconv.Itoa(i)+`}`)
+		builder.AddJsonFile(fileName, false)
+	}
+
+	c, err := builder.Build()
+	if err != nil {
+		b.Fatalf("build failed: %s", err.Error())
+	}
+	cfg := c.(*config)
+
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cfg.reloadSources(func(int, sourceState) bool { return true })
+		}
+	})
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.ReloadSource(fileName)
+		}
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	m := NewConfigMap().
+		Set("db:host", "localhost").
+		Set("db:ports", []interface{}{5432, 5433})
+
+	c, err := NewBuilder().AddMap(m).ReadOnly().Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v, err := c.GetString("db:host")
+				assertVal(t, v, err, "localhost")
+				p, err := c.GetInt("db:ports:1")
+				assertVal(t, p, err, 5433)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err = c.GetOrSet("db:user", func() (interface{}, error) { return "admin", nil }); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("GetOrSet did not return ErrReadOnly: %v", err)
+	}
+	if err = c.ReloadSource("map"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("reload did not return ErrReadOnly: %v", err)
+	}
+
+	_, err = NewBuilder().AddMap(m).ReadOnly().WithRefreshInterval(time.Second).Build()
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("refresh interval did not return ErrReadOnly: %v", err)
+	}
+}
+
+func BenchmarkGetValueReadOnly(b *testing.B) {
+	m := NewConfigMap().
+		Set("a:b:c", "value").
+		Set("a:b:d", 1)
+
+	bench := func(builder *Builder) func(b *testing.B) {
+		return func(b *testing.B) {
+			c, err := builder.Build()
+			if err != nil {
+				b.Fatalf("build failed: %s", err.Error())
+			}
+			ck := CompileKey("a:b:c")
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					c.GetValueCompiled(ck)
+				}
+			})
+		}
+	}
+
+	b.Run("locked", bench(NewBuilder().AddMap(m)))
+	b.Run("read-only", bench(NewBuilder().AddMap(m).ReadOnly()))
+}
+
+// --------------------------------------------------------------------------
+// --- HELPERS
+
+func writeFile(t testing.TB, fileName, content string) {
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+}
+
+type mockProvider struct {
+	mtx   sync.Mutex
+	m     map[string]interface{}
+	err   error
+	calls int
+}
+
+func (p *mockProvider) GetMap() (map[string]interface{}, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.calls++
+	return p.m, p.err
+}
+
+func (p *mockProvider) set(m map[string]interface{}, err error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.m = m
+	p.err = err
+}
+
+func (p *mockProvider) getCalls() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.calls
+}
+
+type mockClosingProvider struct {
+	mockProvider
+	closed   int
+	closeErr error
+}
+
+func (p *mockClosingProvider) Close() error {
+	p.closed++
+	return p.closeErr
+}
+
+// waitFor polls cond until it returns true
+// or a timeout of one second is exceeded.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
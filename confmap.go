@@ -1,33 +1,127 @@
 package configoration
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/zekroTJA/configoration/providers"
+)
 
 // ConfigMap extends map[string]interface{} with
 // functionalities to merge two of them together.
 type ConfigMap map[string]interface{}
 
+// NewConfigMap returns a new, empty ConfigMap
+// which can be filled using Set, for example
+// to pass it to Builder.AddMap.
+func NewConfigMap() ConfigMap {
+	return make(ConfigMap)
+}
+
+// Set sets v for the passed key in m and
+// returns m for chaining. The key is split by
+// the Delimiter and missing sections along the
+// path are created. Values on the path which
+// are not sections are replaced by sections.
+func (m ConfigMap) Set(key string, v interface{}) ConfigMap {
+	segments := splitSections(key)
+	cur := m
+	for _, seg := range segments[:len(segments)-1] {
+		next, ok := toConfigMap(cur[seg])
+		if !ok {
+			next = make(ConfigMap)
+		}
+		cur[seg] = next
+		cur = next
+	}
+	cur[segments[len(segments)-1]] = v
+	return m
+}
+
+// mergeFunc is called by mergeWith for every key
+// which is set in the target map. key is the full
+// path of the key joined by the Delimiter and
+// exists is true if an already existing value
+// has been overwritten. typeChanged is true if a
+// section is replaced by a value or vice versa.
+//
+// If an error is returned, the merge is aborted
+// and the error is returned by mergeWith.
+type mergeFunc func(key string, exists, typeChanged bool) error
+
 // merge combines confMap with m by merging.
 //
 // Existing keys are owerwritten and non-
 // existing keys are added to m.
 func (m ConfigMap) merge(confMap ConfigMap) {
+	m.mergeWith(confMap, "", nil, false)
+}
+
+// mergeWith combines confMap with m like merge
+// and calls onSet, if not nil, for each key set
+// in m. path is the key path of m which is
+// prefixed to passed keys. If patchArrays is
+// set, maps keyed by indices patch the elements
+// of arrays in m instead of replacing them.
+func (m ConfigMap) mergeWith(confMap ConfigMap, path string, onSet mergeFunc, patchArrays bool) error {
 	if confMap == nil {
-		return
+		return nil
 	}
 
 	for k, v := range confMap {
-		if vm, ok := v.(map[interface{}]interface{}); ok {
+		var err error
+		switch vm := v.(type) {
+		case map[interface{}]interface{}:
 			nm := make(map[string]interface{})
 			for k, v := range vm {
 				nm[fmt.Sprintf("%v", k)] = v
 			}
-			m.mergeInnerMap(ConfigMap(nm), k)
-		} else if vm, ok := v.(map[string]interface{}); ok {
-			m.mergeInnerMap(ConfigMap(vm), k)
-		} else {
-			m[k] = v
+			err = m.mergeInnerMapWith(ConfigMap(nm), k, path, onSet, patchArrays)
+		case map[string]interface{}:
+			err = m.mergeInnerMapWith(ConfigMap(vm), k, path, onSet, patchArrays)
+		case ConfigMap:
+			err = m.mergeInnerMapWith(vm, k, path, onSet, patchArrays)
+		case *providers.LazyValue:
+			if !vm.IsObject() || !isSectionValue(m[k]) {
+				err = m.setValueWith(k, vm, path, onSet)
+				break
+			}
+			var dv interface{}
+			if dv, err = decodeLazy(vm); err != nil {
+				break
+			}
+			if inner, ok := dv.(ConfigMap); ok {
+				err = m.mergeInnerMapWith(inner, k, path, onSet, patchArrays)
+			} else {
+				err = m.setValueWith(k, dv, path, onSet)
+			}
+		default:
+			err = m.setValueWith(k, v, path, onSet)
+		}
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
+}
+
+// setValueWith sets the value v for key in m
+// after calling onSet, if not nil.
+func (m ConfigMap) setValueWith(key string, v interface{}, path string, onSet mergeFunc) error {
+	if onSet != nil {
+		existing, exists := m[key]
+		typeChanged := exists && isSectionValue(existing) != isSectionValue(v)
+		if err := onSet(joinPath(path, key), exists, typeChanged); err != nil {
+			return err
+		}
+	}
+
+	m[key] = v
+	return nil
 }
 
 // mergeInnerMap merges confMap with an
@@ -37,15 +131,409 @@ func (m ConfigMap) merge(confMap ConfigMap) {
 // If the value of innerKey is not a
 // ConfigMap, then the function returns.
 func (m ConfigMap) mergeInnerMap(confMap ConfigMap, innerKey string) {
+	m.mergeInnerMapWith(confMap, innerKey, "", nil, false)
+}
+
+// mergeInnerMapWith merges confMap with an
+// inner map of m like mergeInnerMap and calls
+// onSet, if not nil, for each key set in m.
+func (m ConfigMap) mergeInnerMapWith(confMap ConfigMap, innerKey, path string,
+	onSet mergeFunc, patchArrays bool) error {
+
+	innerPath := joinPath(path, innerKey)
+
+	if patchArrays {
+		if vs, ok := toSlice(resolveLazy(m[innerKey])); ok && isIndexPatch(confMap, len(vs)) {
+			return m.patchArrayWith(vs, confMap, innerKey, path, onSet)
+		}
+	}
+
 	if _, ok := m[innerKey]; !ok {
+		if onSet != nil {
+			if err := onSet(innerPath, false, false); err != nil {
+				return err
+			}
+		}
 		m[innerKey] = make(ConfigMap)
 	}
 
+	// lazy sections are replaced by a copy, as
+	// the decoded map is cached by the lazy value
+	// and is reused when the source is not read
+	// again on reload
+	if isLazySection(m[innerKey]) {
+		m[innerKey] = copyValue(m[innerKey])
+	}
+
 	innerMap, ok := m[innerKey].(ConfigMap)
 	if !ok {
+		if onSet != nil {
+			if err := onSet(innerPath, true, true); err != nil {
+				return err
+			}
+		}
 		m[innerKey] = make(ConfigMap)
 		innerMap = m[innerKey].(ConfigMap)
 	}
 
-	innerMap.merge(confMap)
+	return innerMap.mergeWith(confMap, innerPath, onSet, patchArrays)
+}
+
+// isIndexPatch returns true if all keys of
+// patch are indices which are less than the
+// sum of n and the number of keys.
+func isIndexPatch(patch ConfigMap, n int) bool {
+	if len(patch) == 0 {
+		return false
+	}
+	for k := range patch {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= n+len(patch) || strconv.Itoa(i) != k {
+			return false
+		}
+	}
+	return true
+}
+
+// patchArrayWith merges the elements of patch
+// keyed by their index into a copy of vs which
+// is set as innerKey in m. Indices beyond the
+// end of vs append elements and gaps are filled
+// with nil.
+func (m ConfigMap) patchArrayWith(vs []interface{}, patch ConfigMap, innerKey, path string,
+	onSet mergeFunc) error {
+
+	elems := make(ConfigMap, len(vs))
+	for i, e := range vs {
+		elems.mergeWith(ConfigMap{strconv.Itoa(i): e}, "", nil, false)
+	}
+	if err := elems.mergeWith(patch, joinPath(path, innerKey), onSet, true); err != nil {
+		return err
+	}
+
+	n := 0
+	for k := range elems {
+		if i, _ := strconv.Atoi(k); i >= n {
+			n = i + 1
+		}
+	}
+	res := make([]interface{}, n)
+	for k, e := range elems {
+		i, _ := strconv.Atoi(k)
+		res[i] = e
+	}
+
+	m[innerKey] = res
+	return nil
+}
+
+// sectionAt returns the inner map of m at the
+// passed path segments. Lazy sections on the
+// path are replaced by a decoded copy in m.
+func (m ConfigMap) sectionAt(segments []string) (ConfigMap, bool) {
+	cur := m
+	for _, seg := range segments {
+		v := cur[seg]
+		if isLazySection(v) {
+			v = copyValue(v)
+			cur[seg] = v
+		}
+		next, ok := v.(ConfigMap)
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	return cur, true
+}
+
+// fillDefaults sets a copy of all values of
+// defaults for keys which are not set in m.
+// Inner maps which are set in both are filled
+// recursively.
+func (m ConfigMap) fillDefaults(defaults ConfigMap) {
+	for k, dv := range defaults {
+		v, ok := m[k]
+		if !ok {
+			m[k] = copyValue(dv)
+			continue
+		}
+		if isSectionValue(v) && isSectionValue(dv) {
+			vm, ok := v.(ConfigMap)
+			if !ok {
+				vm, _ = copyValue(v).(ConfigMap)
+				m[k] = vm
+			}
+			dm, _ := resolveLazy(dv).(ConfigMap)
+			vm.fillDefaults(dm)
+		}
+	}
+}
+
+// normalizeKeys returns a copy of m with fn
+// applied to all keys of m and its inner maps.
+// If two keys of the same map result in the same
+// normalized key, onCollision is called, if not
+// nil, with the full path of the normalized key.
+// The value of the key which is already equal
+// to the normalized key is kept. Otherwise, the
+// value of the lexically greatest key is kept.
+func normalizeKeys(m map[string]interface{}, fn func(string) string,
+	path string, onCollision func(key string)) map[string]interface{} {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	nm := make(map[string]interface{}, len(m))
+	for _, k := range keys {
+		nk := fn(k)
+		if _, ok := nm[nk]; ok {
+			if onCollision != nil {
+				onCollision(joinPath(path, nk))
+			}
+			if _, normalized := m[nk]; normalized && k != nk {
+				continue
+			}
+		}
+		nm[nk] = normalizeValueKeys(m[k], fn, joinPath(path, nk), onCollision)
+	}
+	return nm
+}
+
+// normalizeValueKeys applies normalizeKeys to v
+// if v is a map or to all elements of v if v
+// is a slice.
+func normalizeValueKeys(v interface{}, fn func(string) string,
+	path string, onCollision func(key string)) interface{} {
+
+	switch vt := v.(type) {
+	case *providers.LazyValue:
+		return normalizeValueKeys(resolveLazy(vt), fn, path, onCollision)
+	case ConfigMap:
+		return ConfigMap(normalizeKeys(vt, fn, path, onCollision))
+	case map[string]interface{}:
+		return normalizeKeys(vt, fn, path, onCollision)
+	case map[interface{}]interface{}:
+		nm := make(map[string]interface{}, len(vt))
+		for k, v := range vt {
+			nm[fmt.Sprintf("%v", k)] = v
+		}
+		return normalizeKeys(nm, fn, path, onCollision)
+	case []interface{}:
+		ns := make([]interface{}, len(vt))
+		for i, e := range vt {
+			ns[i] = normalizeValueKeys(e, fn, joinPath(path, strconv.Itoa(i)), onCollision)
+		}
+		return ns
+	}
+	return v
+}
+
+// joinPath appends key to path separated
+// by the Delimiter.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + Delimiter + key
+}
+
+// copyValue returns a deep copy of v if v is a
+// map or a slice. Otherwise, v is returned as is.
+func copyValue(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case *providers.LazyValue:
+		return copyValue(resolveLazy(vt))
+	case ConfigMap:
+		return vt.copy()
+	case map[string]interface{}:
+		return map[string]interface{}(ConfigMap(vt).copy())
+	case map[interface{}]interface{}:
+		nm := make(map[interface{}]interface{}, len(vt))
+		for k, v := range vt {
+			nm[k] = copyValue(v)
+		}
+		return nm
+	case []interface{}:
+		ns := make([]interface{}, len(vt))
+		for i, v := range vt {
+			ns[i] = copyValue(v)
+		}
+		return ns
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && !rv.IsNil() {
+		ns := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(ns, rv)
+		return ns.Interface()
+	}
+
+	return v
+}
+
+// plainCopy returns a deep copy of v like
+// copyValue but converts all maps to
+// map[string]interface{}.
+func plainCopy(v interface{}) interface{} {
+	switch vt := resolveLazy(v).(type) {
+	case ConfigMap:
+		return plainCopy(map[string]interface{}(vt))
+	case map[string]interface{}:
+		nm := make(map[string]interface{}, len(vt))
+		for k, v := range vt {
+			nm[k] = plainCopy(v)
+		}
+		return nm
+	case map[interface{}]interface{}:
+		nm := make(map[string]interface{}, len(vt))
+		for k, v := range vt {
+			nm[fmt.Sprintf("%v", k)] = plainCopy(v)
+		}
+		return nm
+	case []interface{}:
+		ns := make([]interface{}, len(vt))
+		for i, v := range vt {
+			ns[i] = plainCopy(v)
+		}
+		return ns
+	}
+	return copyValue(v)
+}
+
+// flatten sets all leaf values of v in res
+// keyed by their path below path.
+func flatten(res map[string]string, path string, v interface{}) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, e := range m {
+			flatten(res, joinPath(path, k), e)
+		}
+		return
+	}
+
+	if vs, ok := toSlice(v); ok {
+		for i, e := range vs {
+			flatten(res, joinPath(path, strconv.Itoa(i)), e)
+		}
+		return
+	}
+
+	res[path] = valToString(v)
+}
+
+// valuesEqual returns true if a and b are deeply
+// equal. Maps are equal if they contain the same
+// keys with equal values, regardless of their
+// map types, and slices are equal if they
+// contain equal elements in the same order.
+//
+// Numbers of different types, like int 8080 and
+// float64 8080, are equal if they represent the
+// same value. All other values are compared
+// with reflect.DeepEqual.
+func valuesEqual(a, b interface{}) bool {
+	if la, ok := a.(*providers.LazyValue); ok {
+		if lb, ok := b.(*providers.LazyValue); ok && bytes.Equal(la.Raw(), lb.Raw()) {
+			return true
+		}
+	}
+	a, b = resolveLazy(a), resolveLazy(b)
+
+	if ma, ok := toConfigMap(a); ok {
+		mb, ok := toConfigMap(b)
+		if !ok || len(ma) != len(mb) {
+			return false
+		}
+		for k, va := range ma {
+			vb, ok := mb[k]
+			if !ok || !valuesEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if sa, ok := toSlice(a); ok {
+		sb, ok := toSlice(b)
+		if !ok || len(sa) != len(sb) {
+			return false
+		}
+		for i := range sa {
+			if !valuesEqual(sa[i], sb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if eq, ok := numbersEqual(a, b); ok {
+		return eq
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// numbersEqual compares a and b by their value
+// if both are numbers. Otherwise, ok is false.
+func numbersEqual(a, b interface{}) (eq, ok bool) {
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	ca, cb := numberClass(ra.Kind()), numberClass(rb.Kind())
+	if ca == 0 || cb == 0 {
+		return false, false
+	}
+
+	switch {
+	case ca == 'i' && cb == 'i':
+		return ra.Int() == rb.Int(), true
+	case ca == 'u' && cb == 'u':
+		return ra.Uint() == rb.Uint(), true
+	case ca == 'i' && cb == 'u':
+		return ra.Int() >= 0 && uint64(ra.Int()) == rb.Uint(), true
+	case ca == 'u' && cb == 'i':
+		return rb.Int() >= 0 && uint64(rb.Int()) == ra.Uint(), true
+	}
+	return numberToFloat(ra, ca) == numberToFloat(rb, cb), true
+}
+
+// numberClass returns 'i' for signed integer
+// kinds, 'u' for unsigned integer kinds, 'f' for
+// float kinds and 0 for all other kinds.
+func numberClass(k reflect.Kind) byte {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 'i'
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 'u'
+	case reflect.Float32, reflect.Float64:
+		return 'f'
+	}
+	return 0
+}
+
+// numberToFloat returns the number rv of the
+// passed number class as float64.
+func numberToFloat(rv reflect.Value, class byte) float64 {
+	switch class {
+	case 'i':
+		return float64(rv.Int())
+	case 'u':
+		return float64(rv.Uint())
+	}
+	return rv.Float()
+}
+
+// copy returns a deep copy of m.
+func (m ConfigMap) copy() ConfigMap {
+	if m == nil {
+		return nil
+	}
+
+	nm := make(ConfigMap, len(m))
+	for k, v := range m {
+		nm[k] = copyValue(v)
+	}
+	return nm
 }
@@ -61,6 +61,38 @@ func TestMerheInnerMap(t *testing.T) {
 	assert(t, cm["a"].(ConfigMap)["a3"], 2)
 }
 
+func TestConfigMapSet(t *testing.T) {
+	cm := NewConfigMap().
+		Set("a", 1).
+		Set("b:b1", "x").
+		Set("b:b2:c", true).
+		Set("a:a1", 2)
+
+	assert(t, cm["a"].(ConfigMap)["a1"], 2)
+	assert(t, cm["b"].(ConfigMap)["b1"], "x")
+	assert(t, cm["b"].(ConfigMap)["b2"].(ConfigMap)["c"], true)
+
+	c, err := NewBuilder().
+		AddMap(cm).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		v, err := c.GetString("b:b1")
+		assertVal(t, v, err, "x")
+	}
+	{
+		v, err := c.GetBool("b:b2:c")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetInt("a:a1")
+		assertVal(t, v, err, 2)
+	}
+}
+
 // --------------------------------------------------------------------------
 // --- HELPERS
 
@@ -0,0 +1,251 @@
+package configoration
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zekroTJA/configoration/providers"
+)
+
+// maxIntFloat is the first float64 value which
+// exceeds the range of int.
+const maxIntFloat = float64(1 << (strconv.IntSize - 1))
+
+// toString converts v to a string.
+func toString(v interface{}) string {
+	vt, ok := v.(string)
+	if !ok {
+		vt = valToString(v)
+	}
+	return vt
+}
+
+// toInt converts v to an int. Integral float64
+// values are converted directly and all other
+// values are parsed from their string
+// representation.
+func toInt(v interface{}) (int, error) {
+	switch vt := v.(type) {
+	case int:
+		return vt, nil
+	case string:
+		return parseInt(vt)
+	case float64:
+		if vt == math.Trunc(vt) && vt >= -maxIntFloat && vt < maxIntFloat {
+			return int(vt), nil
+		}
+	}
+	return parseInt(valToString(v))
+}
+
+// parseInt parses s as int. If s is no valid
+// int, it is parsed as float and accepted if it
+// is integral and in the range of int, so that
+// values like "10.0" are read as 10.
+func parseInt(s string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err == nil {
+		return i, nil
+	}
+
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil || f != math.Trunc(f) || f < -maxIntFloat || f >= maxIntFloat {
+		return 0, err
+	}
+	return int(f), nil
+}
+
+// toBool converts v to a bool by parsing its
+// string representation if v is not a bool.
+func toBool(v interface{}) (bool, error) {
+	switch vt := v.(type) {
+	case bool:
+		return vt, nil
+	case string:
+		return strconv.ParseBool(vt)
+	}
+	return strconv.ParseBool(valToString(v))
+}
+
+// toBoolExtended converts v to a bool like toBool
+// but additionally accepts common spellings like
+// yes/no and on/off, case insensitive.
+func toBoolExtended(v interface{}) (bool, error) {
+	if vt, ok := v.(bool); ok {
+		return vt, nil
+	}
+
+	vs := strings.TrimSpace(toString(v))
+	switch strings.ToLower(vs) {
+	case "yes", "y", "on", "enabled":
+		return true, nil
+	case "no", "n", "off", "disabled":
+		return false, nil
+	}
+
+	vt, err := strconv.ParseBool(strings.ToLower(vs))
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean value %q", vs)
+	}
+	return vt, nil
+}
+
+// toDurationSeconds converts v to a duration.
+// Numbers and numeric strings are interpreted
+// as seconds and all other strings are parsed
+// with time.ParseDuration.
+func toDurationSeconds(v interface{}) (time.Duration, error) {
+	if vt, ok := v.(time.Duration); ok {
+		return vt, nil
+	}
+
+	vs := strings.TrimSpace(toString(v))
+	if secs, err := strconv.ParseFloat(vs, 64); err == nil {
+		if math.Abs(secs) > math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("duration %s seconds overflows", vs)
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(vs)
+}
+
+// byteSizeUnits maps the lower case byte size
+// units to their factor. Units without "i" are
+// decimal and units with "i" are binary.
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// toByteSize converts v to a number of bytes.
+// Numbers are interpreted as bytes and strings
+// like "10MB" or "1.5 GiB" are parsed with
+// their unit.
+func toByteSize(v interface{}) (int64, error) {
+	vs := strings.TrimSpace(toString(v))
+	i := strings.IndexFunc(vs, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	if i < 0 {
+		i = len(vs)
+	}
+
+	n, err := strconv.ParseFloat(vs[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", vs)
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(vs[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit in %q", vs)
+	}
+
+	size := n * unit
+	if size != math.Trunc(size) || math.Abs(size) >= math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q is no integral int64", vs)
+	}
+	return int64(size), nil
+}
+
+// toFloat64 converts v to a float64. Other
+// number types are converted directly and all
+// other values are parsed from their string
+// representation.
+func toFloat64(v interface{}) (float64, error) {
+	switch vt := v.(type) {
+	case float64:
+		return vt, nil
+	case int:
+		return float64(vt), nil
+	case string:
+		return strconv.ParseFloat(vt, 64)
+	}
+	return strconv.ParseFloat(valToString(v), 64)
+}
+
+// toSlice returns the elements of v if v is
+// a slice or an array. Otherwise, ok is false.
+func toSlice(v interface{}) (s []interface{}, ok bool) {
+	if vt, ok := v.([]interface{}); ok {
+		return vt, true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	s = make([]interface{}, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s, true
+}
+
+// inferValue parses v using providers.InferType
+// if v is a string. Values of maps and slices
+// are inferred in place.
+func inferValue(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case string:
+		return providers.InferType(vt)
+	case ConfigMap:
+		for k, e := range vt {
+			vt[k] = inferValue(e)
+		}
+	case map[string]interface{}:
+		for k, e := range vt {
+			vt[k] = inferValue(e)
+		}
+	case []interface{}:
+		for i, e := range vt {
+			vt[i] = inferValue(e)
+		}
+	}
+	return v
+}
+
+// expandEnvTokens replaces all ${VAR} tokens in
+// v by the value of the environment variable
+// VAR. Unset variables are replaced by an empty
+// string and unterminated tokens are kept.
+func expandEnvTokens(v string) string {
+	if !strings.Contains(v, "${") {
+		return v
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(v, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(v[start:], '}')
+		if end < 0 {
+			break
+		}
+		sb.WriteString(v[:start])
+		sb.WriteString(os.Getenv(v[start+2 : start+end]))
+		v = v[start+end+1:]
+	}
+	sb.WriteString(v)
+
+	return sb.String()
+}
@@ -0,0 +1,311 @@
+package configoration
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const structTag = "config"
+
+// byteSizeTagOption marks integer fields whose
+// values are parsed as byte sizes like "10MB".
+const byteSizeTagOption = "bytesize"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// GetValueAs resolves the value of key in s and
+// decodes it into out.
+//
+// Scalars are converted like by the typed
+// getters, slices are decoded element by element
+// and sections are decoded into maps with string
+// keys or into structs. Struct fields are matched
+// by their `config:"name"` tag or by their field
+// name, falling back to a case insensitive match.
+// Fields tagged with `config:"-"` are skipped.
+//
+// time.Duration values are parsed like by
+// GetDurationSeconds. Integer fields tagged
+// with the bytesize option like
+// `config:"max,bytesize"` are parsed like by
+// GetByteSize.
+//
+// If the key was not found, ErrNil is returned.
+// If the value does not match the shape of out,
+// ErrInvalidType is returned.
+func GetValueAs[T any](s Section, key string, out *T) error {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return err
+	}
+
+	return decodeInto(key, v, out)
+}
+
+// GetOrDef resolves the value of key in s and
+// converts it to T like GetValueAs. If the key
+// was not found or the value can not be
+// converted, def is returned.
+func GetOrDef[T any](s Section, key string, def T) T {
+	if s == nil {
+		return def
+	}
+
+	var v T
+	if err := GetValueAs(s, key, &v); err != nil {
+		if r, ok := s.(interface{ reportTypeErr(err error) }); ok {
+			r.reportTypeErr(err)
+		}
+		return def
+	}
+
+	return v
+}
+
+func (s *section) UnmarshalKey(key string, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrInvalidTarget
+	}
+
+	v, err := s.GetValue(key)
+	if err != nil {
+		return err
+	}
+
+	return decodeInto(key, v, target)
+}
+
+// decodeInto decodes v into the value pointed to
+// by target, which must be a non-nil pointer.
+// target is only modified if v could be decoded
+// successfully.
+func decodeInto(key string, v interface{}, target interface{}) error {
+	rv := reflect.ValueOf(target).Elem()
+
+	res := reflect.New(rv.Type()).Elem()
+	if err := decodeValue(key, v, res); err != nil {
+		return err
+	}
+
+	rv.Set(res)
+	return nil
+}
+
+// decodeValue decodes v into rv. key is the
+// path of v which is used for errors.
+func decodeValue(key string, v interface{}, rv reflect.Value) error {
+	if v == nil {
+		return nil
+	}
+
+	if vv := reflect.ValueOf(v); vv.Type().AssignableTo(rv.Type()) {
+		rv.Set(vv)
+		return nil
+	}
+
+	if rv.Type() == durationType {
+		vt, err := toDurationSeconds(v)
+		if err != nil {
+			return newConversionError(key, err)
+		}
+		rv.SetInt(int64(vt))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(rv.Type().Elem())
+		if err := decodeValue(key, v, elem.Elem()); err != nil {
+			return err
+		}
+		rv.Set(elem)
+
+	case reflect.String:
+		rv.SetString(toString(v))
+
+	case reflect.Bool:
+		vt, err := toBool(v)
+		if err != nil {
+			return newConversionError(key, err)
+		}
+		rv.SetBool(vt)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		vt, err := toInt(v)
+		if err != nil {
+			return newConversionError(key, err)
+		}
+		if rv.OverflowInt(int64(vt)) {
+			return newConversionError(key, fmt.Errorf("value %d overflows %s", vt, rv.Type()))
+		}
+		rv.SetInt(int64(vt))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		vt, err := toInt(v)
+		if err != nil {
+			return newConversionError(key, err)
+		}
+		if vt < 0 || rv.OverflowUint(uint64(vt)) {
+			return newConversionError(key, fmt.Errorf("value %d overflows %s", vt, rv.Type()))
+		}
+		rv.SetUint(uint64(vt))
+
+	case reflect.Float32, reflect.Float64:
+		vt, err := toFloat64(v)
+		if err != nil {
+			return newConversionError(key, err)
+		}
+		if rv.OverflowFloat(vt) {
+			return newConversionError(key, fmt.Errorf("value %v overflows %s", vt, rv.Type()))
+		}
+		rv.SetFloat(vt)
+
+	case reflect.Slice:
+		vs, ok := toSlice(v)
+		if !ok {
+			return newKeyError(key, ErrInvalidType)
+		}
+		res := reflect.MakeSlice(rv.Type(), len(vs), len(vs))
+		for i, e := range vs {
+			if err := decodeValue(joinPath(key, strconv.Itoa(i)), e, res.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(res)
+
+	case reflect.Map:
+		m, ok := toConfigMap(v)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return newKeyError(key, ErrInvalidType)
+		}
+		res := reflect.MakeMapWithSize(rv.Type(), len(m))
+		for k, e := range m {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(joinPath(key, k), e, ev); err != nil {
+				return err
+			}
+			res.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+		}
+		rv.Set(res)
+
+	case reflect.Struct:
+		m, ok := toConfigMap(v)
+		if !ok {
+			return newKeyError(key, ErrInvalidType)
+		}
+		return decodeStruct(key, m, rv)
+
+	default:
+		return newKeyError(key, ErrInvalidType)
+	}
+
+	return nil
+}
+
+// decodeStruct decodes the values of m into the
+// fields of the struct value rv.
+func decodeStruct(key string, m map[string]interface{}, rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(structTag)
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && !ok && field.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(key, m, rv.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if !ok || name == "" {
+			name = field.Name
+		}
+
+		k, v, found := lookupField(m, name)
+		if !found {
+			continue
+		}
+		if hasTagOption(opts, byteSizeTagOption) {
+			err := decodeByteSize(joinPath(key, k), v, rv.Field(i))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := decodeValue(joinPath(key, k), v, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeByteSize decodes v as byte size into
+// the integer value rv.
+func decodeByteSize(key string, v interface{}, rv reflect.Value) error {
+	if v == nil {
+		return nil
+	}
+
+	vt, err := toByteSize(v)
+	if err != nil {
+		return newConversionError(key, err)
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.OverflowInt(vt) {
+			return newConversionError(key, fmt.Errorf("value %d overflows %s", vt, rv.Type()))
+		}
+		rv.SetInt(vt)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if vt < 0 || rv.OverflowUint(uint64(vt)) {
+			return newConversionError(key, fmt.Errorf("value %d overflows %s", vt, rv.Type()))
+		}
+		rv.SetUint(uint64(vt))
+	default:
+		return newKeyError(key, ErrInvalidType)
+	}
+
+	return nil
+}
+
+// hasTagOption returns true if the comma
+// separated tag options contain opt.
+func hasTagOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField returns the key and value of name
+// in m. If name is not found, a case insensitive
+// match is returned, if existent.
+func lookupField(m map[string]interface{}, name string) (string, interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return name, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
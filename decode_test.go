@@ -0,0 +1,280 @@
+package configoration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetValueAsScalar(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"i":   1,
+		"s":   "2",
+		"f":   "1.5",
+		"big": 1000,
+	})
+
+	{
+		var rec int
+		err := GetValueAs(s, "s", &rec)
+		assertVal(t, rec, err, 2)
+	}
+	{
+		var rec string
+		err := GetValueAs(s, "i", &rec)
+		assertVal(t, rec, err, "1")
+	}
+	{
+		var rec float32
+		err := GetValueAs(s, "f", &rec)
+		assertVal(t, rec, err, float32(1.5))
+	}
+	{
+		var rec *int
+		err := GetValueAs(s, "i", &rec)
+		if err != nil || rec == nil || *rec != 1 {
+			t.Errorf("recovered pointer was not like expected: %v", err)
+		}
+	}
+	{
+		var rec int8
+		err := GetValueAs(s, "big", &rec)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("overflow did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		var rec int
+		err := GetValueAs(s, "none", &rec)
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetOrDef(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"s": "value",
+		"i": 42,
+		"b": "true",
+		"f": 1.5,
+		"d": "30s",
+		"n": "not a number",
+	})
+
+	assert(t, GetOrDef(s, "s", "def"), "value")
+	assert(t, GetOrDef(s, "none", "def"), "def")
+
+	assert(t, GetOrDef(s, "i", 1), 42)
+	assert(t, GetOrDef(s, "n", 1), 1)
+	assert(t, GetOrDef(s, "none", 1), 1)
+
+	assert(t, GetOrDef(s, "b", false), true)
+	assert(t, GetOrDef(s, "n", false), false)
+
+	assert(t, GetOrDef(s, "f", 0.5), 1.5)
+	assert(t, GetOrDef(s, "n", 0.5), 0.5)
+
+	assert(t, GetOrDef(s, "d", time.Second), 30*time.Second)
+	assert(t, GetOrDef(s, "n", time.Second), time.Second)
+	assert(t, GetOrDef(s, "none", time.Second), time.Second)
+}
+
+func TestGetValueAsSlice(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"ints":  []interface{}{1, "2", 3.0},
+		"mixed": []interface{}{1, "a"},
+		"s":     "a",
+	})
+
+	{
+		var rec []int
+		err := GetValueAs(s, "ints", &rec)
+		assertSlice(t, rec, err, []int{1, 2, 3})
+	}
+	{
+		var rec []int
+		err := GetValueAs(s, "mixed", &rec)
+		var kErr *KeyError
+		if !errors.Is(err, ErrInvalidType) || !errors.As(err, &kErr) || kErr.Key != "mixed:1" {
+			t.Errorf("invalid element did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		var rec []int
+		err := GetValueAs(s, "s", &rec)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("scalar value did not return ErrInvalidType: %v", err)
+		}
+	}
+}
+
+func TestGetValueAsStruct(t *testing.T) {
+	type base struct {
+		Name string
+	}
+	type webserver struct {
+		base
+		Addr    string `config:"address"`
+		Port    int
+		TLS     bool
+		Ignored string `config:"-"`
+		Headers map[string]string
+		Origins []string
+	}
+
+	s := makeSection(ConfigMap{
+		"webserver": ConfigMap{
+			"name":    "main",
+			"address": "localhost",
+			"port":    "8080",
+			"tls":     true,
+			"Ignored": "ignored",
+			"headers": ConfigMap{
+				"x-test": "a",
+			},
+			"origins": []interface{}{"a", "b"},
+		},
+		"invalid": ConfigMap{
+			"port": "abc",
+		},
+	})
+
+	{
+		var rec webserver
+		err := GetValueAs(s, "webserver", &rec)
+		if err != nil {
+			t.Fatalf("decoding failed: %s", err.Error())
+		}
+		assert(t, rec.Name, "main")
+		assert(t, rec.Addr, "localhost")
+		assert(t, rec.Port, 8080)
+		assert(t, rec.TLS, true)
+		assert(t, rec.Ignored, "")
+		assert(t, rec.Headers["x-test"], "a")
+		assertSlice(t, rec.Origins, nil, []string{"a", "b"})
+	}
+	{
+		var rec webserver
+		err := GetValueAs(s, "invalid", &rec)
+		var kErr *KeyError
+		if !errors.Is(err, ErrInvalidType) || !errors.As(err, &kErr) || kErr.Key != "invalid:port" {
+			t.Errorf("invalid field did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		var rec webserver
+		err := GetValueAs(s, "webserver:port", &rec)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("scalar value did not return ErrInvalidType: %v", err)
+		}
+	}
+}
+
+func TestUnmarshalKeyMap(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"limits": ConfigMap{
+			"api": 100,
+			"web": "50",
+		},
+		"names": ConfigMap{
+			"a": "alpha",
+			"b": 2,
+		},
+		"invalid": ConfigMap{
+			"api": "many",
+		},
+	})
+
+	{
+		var rec map[string]int
+		err := s.UnmarshalKey("limits", &rec)
+		if err != nil {
+			t.Fatalf("decoding failed: %s", err.Error())
+		}
+		assert(t, len(rec), 2)
+		assert(t, rec["api"], 100)
+		assert(t, rec["web"], 50)
+	}
+	{
+		var rec map[string]string
+		err := s.UnmarshalKey("names", &rec)
+		if err != nil {
+			t.Fatalf("decoding failed: %s", err.Error())
+		}
+		assert(t, rec["a"], "alpha")
+		assert(t, rec["b"], "2")
+	}
+	{
+		rec := map[string]int{"old": 1}
+		err := s.UnmarshalKey("invalid", &rec)
+		var kErr *KeyError
+		if !errors.Is(err, ErrInvalidType) || !errors.As(err, &kErr) || kErr.Key != "invalid:api" {
+			t.Errorf("invalid element did not return ErrInvalidType: %v", err)
+		}
+		if len(rec) != 1 || rec["old"] != 1 {
+			t.Error("target was modified on error")
+		}
+	}
+	{
+		var rec map[string]int
+		err := s.UnmarshalKey("limits:api", &rec)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("scalar value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		var rec map[string]int
+		if err := s.UnmarshalKey("limits", rec); err != ErrInvalidTarget {
+			t.Errorf("non-pointer target did not return ErrInvalidTarget: %v", err)
+		}
+	}
+	{
+		var rec map[string]int
+		err := s.UnmarshalKey("none", &rec)
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestUnmarshalKeyDurationAndByteSize(t *testing.T) {
+	type upload struct {
+		Timeout time.Duration `config:"timeout"`
+		Max     int64         `config:"max,bytesize"`
+		Chunk   uint32        `config:"chunk,bytesize"`
+	}
+
+	s := makeSection(ConfigMap{
+		"upload": ConfigMap{
+			"timeout": "30s",
+			"max":     "10MB",
+			"chunk":   "512 KiB",
+		},
+		"invalid": ConfigMap{
+			"max": "10 parsecs",
+		},
+	})
+
+	{
+		var rec upload
+		err := s.UnmarshalKey("upload", &rec)
+		if err != nil {
+			t.Fatalf("decoding failed: %s", err.Error())
+		}
+		assert(t, rec.Timeout, 30*time.Second)
+		assert(t, rec.Max, int64(10_000_000))
+		assert(t, rec.Chunk, uint32(512*1024))
+	}
+	{
+		var rec upload
+		err := s.UnmarshalKey("invalid", &rec)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("invalid byte size did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		rec, err := s.GetByteSize("upload:chunk")
+		assertVal(t, rec, err, int64(512*1024))
+	}
+}
@@ -0,0 +1,69 @@
+package configoration
+
+import "sort"
+
+// Change describes a value which differs
+// between two versions of a config.
+type Change struct {
+	// Key is the full path of the changed
+	// value.
+	Key string
+
+	// Old is the previous value, which is nil
+	// if the key was added or was null.
+	Old interface{}
+
+	// New is the current value, which is nil
+	// if the key was removed or is null.
+	New interface{}
+}
+
+// diffValues returns the changes from old to
+// new sorted by key. Sections are compared key
+// by key and all other values as a whole.
+func diffValues(old, new ConfigMap) []Change {
+	var changes []Change
+	diffValue(&changes, "", old, new)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+func diffValue(changes *[]Change, path string, old, new interface{}) {
+	old, new = resolveLazy(old), resolveLazy(new)
+
+	mo, okOld := toConfigMap(old)
+	mn, okNew := toConfigMap(new)
+	if okOld && okNew {
+		// keys are compared by presence, so that
+		// removed or added null values are changes
+		for k, vo := range mo {
+			if vn, ok := mn[k]; ok {
+				diffValue(changes, joinPath(path, k), vo, vn)
+			} else {
+				addChange(changes, joinPath(path, k), vo, nil)
+			}
+		}
+		for k, vn := range mn {
+			if _, ok := mo[k]; !ok {
+				addChange(changes, joinPath(path, k), nil, vn)
+			}
+		}
+		return
+	}
+
+	if !valuesEqual(old, new) {
+		addChange(changes, path, old, new)
+	}
+}
+
+// addChange appends a change of the value of
+// key from old to new to changes.
+func addChange(changes *[]Change, key string, old, new interface{}) {
+	*changes = append(*changes, Change{
+		Key: key,
+		Old: plainCopy(resolveLazy(old)),
+		New: plainCopy(resolveLazy(new)),
+	})
+}
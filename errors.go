@@ -1,6 +1,9 @@
 package configoration
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNil is returned when the selected
@@ -11,4 +14,238 @@ var (
 	// selected value is not the requested
 	// value type
 	ErrInvalidType = errors.New("invalid value type")
+
+	// ErrExpectedScalar is returned by scalar
+	// getters like GetString when the selected
+	// value is a slice. It wraps ErrInvalidType.
+	ErrExpectedScalar = fmt.Errorf("expected scalar but value is a slice: %w", ErrInvalidType)
+
+	// ErrTypeConflict is returned on build in
+	// strict merge mode when a key is defined
+	// as section in one source and as value in
+	// another source.
+	ErrTypeConflict = errors.New("key is defined as section and value")
+
+	// ErrRequiredKeyMissing is returned by the
+	// *Required getters when the selected value
+	// does not exist. It also matches ErrNil.
+	ErrRequiredKeyMissing = errors.New("required key is missing")
+
+	// ErrInvalidTarget is returned when the
+	// target passed to UnmarshalKey is not a
+	// non-nil pointer.
+	ErrInvalidTarget = errors.New("target must be a non-nil pointer")
+
+	// ErrEmptySource is returned on build when
+	// failing on empty sources is enabled and a
+	// non-optional source provided no values.
+	ErrEmptySource = errors.New("source provided no values")
+
+	// ErrUnknownSource is returned by
+	// ReloadSource when no source has the
+	// passed name.
+	ErrUnknownSource = errors.New("unknown source")
+
+	// ErrAmbiguousSource is returned by
+	// GetFromSource when multiple sources have
+	// the passed name.
+	ErrAmbiguousSource = errors.New("ambiguous source name")
+
+	// ErrReadOnly is returned when a config
+	// built with Builder.ReadOnly would be
+	// modified, like by a reload.
+	ErrReadOnly = errors.New("config is read-only")
+
+	// ErrUnsupportedFormat is returned on build
+	// when a file passed to AddFile has an
+	// extension which is not supported.
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+
+	// ErrValidationFailed is matched by all
+	// errors returned for violated validation
+	// rules by BindSchema and ValidateStruct.
+	ErrValidationFailed = errors.New("validation failed")
 )
+
+// KeyErrorKind describes why the lookup of a
+// key failed.
+type KeyErrorKind int
+
+const (
+	// KindOther is set for all errors which are
+	// not caused by the traversal of the key,
+	// like conversion errors.
+	KindOther KeyErrorKind = iota
+
+	// KindSectionMissing is set when an
+	// intermediate section of the key does
+	// not exist.
+	KindSectionMissing
+
+	// KindKeyMissing is set when all sections
+	// of the key exist but the last key does
+	// not exist in the last section.
+	KindKeyMissing
+
+	// KindNotSection is set when an
+	// intermediate section of the key is a
+	// value and not a section.
+	KindNotSection
+)
+
+func (k KeyErrorKind) String() string {
+	switch k {
+	case KindSectionMissing:
+		return "section does not exist"
+	case KindKeyMissing:
+		return "key does not exist in section"
+	case KindNotSection:
+		return "value is not a section"
+	}
+	return "other"
+}
+
+// KeyError wraps an error which occured while
+// accessing the value of Key.
+//
+// Use errors.Is to check for the wrapped
+// sentinel errors like ErrNil or ErrInvalidType.
+type KeyError struct {
+	// Key is the requested key.
+	Key string
+
+	// Err is the wrapped error.
+	Err error
+
+	// Kind describes why the traversal of Key
+	// failed. For errors which are not caused
+	// by the traversal, it is KindOther.
+	Kind KeyErrorKind
+
+	// Path is the path of the section at which
+	// the traversal failed. For KindKeyMissing,
+	// it is the path of the section which does
+	// not contain the last key, which is empty
+	// for the root section. For
+	// KindSectionMissing and KindNotSection,
+	// it is the path of the failing section.
+	Path string
+}
+
+func (e *KeyError) Error() string {
+	if e.Kind != KindOther {
+		return fmt.Sprintf("key %q: %s (%s: %q)", e.Key, e.Err.Error(), e.Kind, e.Path)
+	}
+	return fmt.Sprintf("key %q: %s", e.Key, e.Err.Error())
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// SourceError wraps an error which occured while
+// reading the config values of a source.
+type SourceError struct {
+	// Source is the name of the source.
+	Source string
+
+	// Err is the wrapped error.
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("source %s: %s", e.Source, e.Err.Error())
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when the value of
+// Key violates a validation rule.
+type ValidationError struct {
+	// Key is the config key of the value.
+	Key string
+
+	// Rule is the violated rule like "min=1".
+	Rule string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("key %q: violates rule %q", e.Key, e.Rule)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// conversionError wraps an error which occured
+// while converting a value to the requested type.
+// It matches ErrInvalidType and unwraps to the
+// original conversion error.
+type conversionError struct {
+	err error
+}
+
+func (e *conversionError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidType.Error(), e.err.Error())
+}
+
+func (e *conversionError) Is(target error) bool {
+	return target == ErrInvalidType
+}
+
+func (e *conversionError) Unwrap() error {
+	return e.err
+}
+
+// requiredError is returned when a required
+// value does not exist. It matches
+// ErrRequiredKeyMissing and ErrNil.
+type requiredError struct{}
+
+func (e *requiredError) Error() string {
+	return ErrRequiredKeyMissing.Error()
+}
+
+func (e *requiredError) Is(target error) bool {
+	return target == ErrRequiredKeyMissing || target == ErrNil
+}
+
+// newKeyError returns a new *KeyError wrapping
+// err for the passed key.
+func newKeyError(key string, err error) error {
+	return &KeyError{Key: key, Err: err}
+}
+
+// newTraversalError returns a new *KeyError
+// wrapping ErrNil for the passed key, failing
+// section path and kind.
+func newTraversalError(key, path string, kind KeyErrorKind) error {
+	return &KeyError{Key: key, Err: ErrNil, Kind: kind, Path: path}
+}
+
+// newConversionError returns a new *KeyError
+// wrapping the conversion error err so that it
+// matches ErrInvalidType.
+func newConversionError(key string, err error) error {
+	return newKeyError(key, &conversionError{err: err})
+}
+
+// requiredErr returns a *KeyError matching
+// ErrRequiredKeyMissing for key, keeping the
+// kind and path of err, if err matches ErrNil.
+// Otherwise, err is returned as is.
+func requiredErr(key string, err error) error {
+	if !errors.Is(err, ErrNil) {
+		return err
+	}
+
+	res := &KeyError{Key: key, Err: &requiredError{}}
+	var keyErr *KeyError
+	if errors.As(err, &keyErr) {
+		res.Kind = keyErr.Kind
+		res.Path = keyErr.Path
+	}
+	return res
+}
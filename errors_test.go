@@ -0,0 +1,148 @@
+package configoration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestKeyErrorIs(t *testing.T) {
+	s := makeDefSection()
+
+	{
+		_, err := s.GetString("a:none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("missing key error did not match ErrNil")
+		}
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || keyErr.Key != "a:none" {
+			t.Errorf("missing key error (%+v) did not carry the key", err)
+		}
+	}
+	{
+		_, err := s.GetInt("a:s")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Error("conversion error did not match ErrInvalidType")
+		}
+		if !errors.Is(err, strconv.ErrSyntax) {
+			t.Error("conversion error did not unwrap to the strconv error")
+		}
+		if errors.Is(err, ErrNil) {
+			t.Error("conversion error matched ErrNil")
+		}
+	}
+	{
+		_, err := s.GetBool("none:none")
+		wrapped := fmt.Errorf("wrapped: %w", err)
+		if !errors.Is(wrapped, ErrNil) {
+			t.Error("wrapped missing key error did not match ErrNil")
+		}
+	}
+	{
+		_, err := s.GetFloat64("a:s")
+		wrapped := fmt.Errorf("wrapped: %w", err)
+		if !errors.Is(wrapped, ErrInvalidType) {
+			t.Error("wrapped conversion error did not match ErrInvalidType")
+		}
+	}
+}
+
+func TestKeyErrorKind(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"b": ConfigMap{"c": 1},
+			"s": "value",
+		},
+	})
+
+	cases := []struct {
+		key  string
+		kind KeyErrorKind
+		path string
+	}{
+		{"a:x:c", KindSectionMissing, "a:x"},
+		{"x:b:c", KindSectionMissing, "x"},
+		{"a:b:x", KindKeyMissing, "a:b"},
+		{"x", KindKeyMissing, ""},
+		{"a:s:c", KindNotSection, "a:s"},
+	}
+
+	for _, c := range cases {
+		_, err := s.GetValue(c.key)
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("%s: error did not match ErrNil: %v", c.key, err)
+		}
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) {
+			t.Fatalf("%s: error was no *KeyError: %v", c.key, err)
+		}
+		if keyErr.Kind != c.kind || keyErr.Path != c.path {
+			t.Errorf("%s: kind (%s) and path (%q) were not like expected (%s, %q)",
+				c.key, keyErr.Kind, keyErr.Path, c.kind, c.path)
+		}
+	}
+
+	{
+		_, err := s.GetInt("a:s")
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || keyErr.Kind != KindOther {
+			t.Errorf("conversion error kind was not KindOther: %v", err)
+		}
+	}
+}
+
+func TestRequiredErrors(t *testing.T) {
+	s := makeDefSection()
+
+	{
+		v, err := s.GetStringRequired("a:s")
+		assertVal(t, v, err, "test123")
+	}
+
+	for key, get := range map[string]func(string) error{
+		"string": func(k string) error { _, err := s.GetStringRequired(k); return err },
+		"int":    func(k string) error { _, err := s.GetIntRequired(k); return err },
+		"bool":   func(k string) error { _, err := s.GetBoolRequired(k); return err },
+		"float":  func(k string) error { _, err := s.GetFloat64Required(k); return err },
+	} {
+		err := get("a:none")
+		if !errors.Is(err, ErrRequiredKeyMissing) || !errors.Is(err, ErrNil) {
+			t.Errorf("%s: missing key error did not match ErrRequiredKeyMissing: %v", key, err)
+		}
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || keyErr.Key != "a:none" {
+			t.Errorf("%s: missing key error (%+v) did not carry the key", key, err)
+		}
+		if err = get("none:none"); !errors.Is(err, ErrRequiredKeyMissing) {
+			t.Errorf("%s: missing section error did not match ErrRequiredKeyMissing: %v", key, err)
+		}
+	}
+
+	for key, get := range map[string]func(string) error{
+		"int":   func(k string) error { _, err := s.GetIntRequired(k); return err },
+		"bool":  func(k string) error { _, err := s.GetBoolRequired(k); return err },
+		"float": func(k string) error { _, err := s.GetFloat64Required(k); return err },
+	} {
+		err := get("a:s")
+		if !errors.Is(err, ErrInvalidType) || errors.Is(err, ErrRequiredKeyMissing) {
+			t.Errorf("%s: wrong type error was not like expected: %v", key, err)
+		}
+	}
+}
+
+func TestSourceErrorIs(t *testing.T) {
+	srcErr := errors.New("source error")
+
+	_, err := NewBuilder().
+		AddProvider(&mockProvider{err: srcErr}).
+		Build()
+
+	if !errors.Is(err, srcErr) {
+		t.Error("build error did not match the source error")
+	}
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Source != "*configoration.mockProvider" {
+		t.Errorf("build error (%+v) did not carry the source name", err)
+	}
+}
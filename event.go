@@ -0,0 +1,81 @@
+package configoration
+
+import "time"
+
+// EventType describes what happened when an
+// Event is emitted.
+type EventType int
+
+const (
+	// EventSourceLoaded is emitted after a
+	// source has been read successfully.
+	EventSourceLoaded EventType = iota
+
+	// EventSourceFailed is emitted when reading
+	// a source failed. Err contains the error.
+	EventSourceFailed
+
+	// EventKeyOverridden is emitted when a
+	// source overrides a value which has been
+	// set by a previous source.
+	EventKeyOverridden
+
+	// EventBuilt is emitted after the config
+	// has been built.
+	EventBuilt
+
+	// EventReloaded is emitted after the config
+	// has been reloaded. If the reload failed,
+	// Err contains the error.
+	EventReloaded
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSourceLoaded:
+		return "source loaded"
+	case EventSourceFailed:
+		return "source failed"
+	case EventKeyOverridden:
+		return "key overridden"
+	case EventBuilt:
+		return "built"
+	case EventReloaded:
+		return "reloaded"
+	}
+	return "unknown"
+}
+
+// Event is passed to the logger registered with
+// Builder.WithLogger.
+type Event struct {
+	// Type describes what happened.
+	Type EventType
+
+	// Source is the name of the source the
+	// event refers to, if any.
+	Source string
+
+	// Key is the overridden key for
+	// EventKeyOverridden.
+	Key string
+
+	// OverriddenSource is the name of the source
+	// which set the value before for
+	// EventKeyOverridden.
+	OverriddenSource string
+
+	// Duration is the time it took to read the
+	// source or to build or reload the config.
+	Duration time.Duration
+
+	// Err is the error which occured, if any.
+	Err error
+}
+
+// emit passes e to the logger of b, if set.
+func (b *Builder) emit(e Event) {
+	if b.logger != nil {
+		b.logger(e)
+	}
+}
@@ -0,0 +1,160 @@
+package configoration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zekroTJA/configoration/providers"
+	"gopkg.in/yaml.v2"
+)
+
+func (c *config) Export(format string) ([]byte, error) {
+	c.mtx.Lock()
+	v := orderedValue(c.m, "", c.order)
+	c.mtx.Unlock()
+
+	switch strings.ToLower(format) {
+	case "json":
+		return json.MarshalIndent(v, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(v)
+	}
+
+	return nil, fmt.Errorf("%s: %w", format, ErrUnsupportedFormat)
+}
+
+// orderedMap is a map whose keys are encoded in
+// the order of its items.
+type orderedMap yaml.MapSlice
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (m orderedMap) MarshalYAML() (interface{}, error) {
+	return yaml.MapSlice(m), nil
+}
+
+// orderedValue returns a copy of v at path in
+// which all maps are converted to orderedMap.
+// The keys of each map are ordered as recorded
+// in order, followed by all other keys sorted.
+func orderedValue(v interface{}, path string, order providers.KeyOrder) interface{} {
+	v = resolveLazy(v)
+
+	if m, ok := toConfigMap(v); ok {
+		keys := make([]string, 0, len(m))
+		seen := make(map[string]struct{}, len(m))
+		for _, k := range order[path] {
+			if _, ok := m[k]; ok {
+				keys = append(keys, k)
+				seen[k] = struct{}{}
+			}
+		}
+		rest := make([]string, 0, len(m)-len(keys))
+		for k := range m {
+			if _, ok := seen[k]; !ok {
+				rest = append(rest, k)
+			}
+		}
+		sort.Strings(rest)
+
+		res := make(orderedMap, 0, len(m))
+		for _, k := range append(keys, rest...) {
+			res = append(res, yaml.MapItem{Key: k, Value: orderedValue(m[k], joinPath(path, k), order)})
+		}
+		return res
+	}
+
+	if vs, ok := toSlice(v); ok {
+		res := make([]interface{}, len(vs))
+		for i, e := range vs {
+			res[i] = orderedValue(e, joinPath(path, strconv.Itoa(i)), order)
+		}
+		return res
+	}
+
+	return v
+}
+
+// mergeKeyOrder returns the key order of all
+// states. Keys which are first defined by a
+// later source follow the keys of earlier
+// sources. If a root key is set, only the
+// order within the root key is returned.
+func (b *Builder) mergeKeyOrder(states []sourceState) providers.KeyOrder {
+	res := make(providers.KeyOrder)
+	seen := make(map[string]map[string]struct{})
+	for _, st := range states {
+		for path, keys := range st.order {
+			if seen[path] == nil {
+				seen[path] = make(map[string]struct{})
+			}
+			for _, k := range keys {
+				if _, ok := seen[path][k]; !ok {
+					seen[path][k] = struct{}{}
+					res[path] = append(res[path], k)
+				}
+			}
+		}
+	}
+
+	if b.rootKey == "" {
+		return res
+	}
+
+	root := strings.Join(b.normalizePath(b.rootKey), Delimiter)
+	rooted := make(providers.KeyOrder)
+	for path, keys := range res {
+		if path == root {
+			rooted[""] = keys
+		} else if strings.HasPrefix(path, root+Delimiter) {
+			rooted[path[len(root)+len(Delimiter):]] = keys
+		}
+	}
+	return rooted
+}
+
+// normalizeKeyOrder applies the key normalizer,
+// if set, to all paths and keys of order.
+func (b *Builder) normalizeKeyOrder(order providers.KeyOrder) providers.KeyOrder {
+	if b.keyNormalizer == nil || order == nil {
+		return order
+	}
+
+	res := make(providers.KeyOrder, len(order))
+	for path, keys := range order {
+		if path != "" {
+			path = strings.Join(b.normalizePath(path), Delimiter)
+		}
+		nkeys := make([]string, len(keys))
+		for i, k := range keys {
+			nkeys[i] = b.keyNormalizer(k)
+		}
+		res[path] = nkeys
+	}
+	return res
+}
@@ -0,0 +1,110 @@
+package configoration
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "config.json")
+	yamlFile := filepath.Join(dir, "override.yaml")
+	writeFile(t, jsonFile, `{"zeta": 1, "alpha": {"y": true, "b": [{"q": 1, "p": 2}]}, "mid": "x"}`)
+	writeFile(t, yamlFile, "mid: z\nbeta: 2\nalpha:\n  c: 3\n")
+
+	b := NewBuilder().
+		SetPreserveKeyOrder(true).
+		AddJsonFile(jsonFile, false).
+		AddYamlFile(yamlFile, false)
+	c, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	{
+		data, err := c.Export("json")
+		assertVal(t, string(data), err, `{
+  "zeta": 1,
+  "alpha": {
+    "y": true,
+    "b": [
+      {
+        "q": 1,
+        "p": 2
+      }
+    ],
+    "c": 3
+  },
+  "mid": "z",
+  "beta": 2
+}`)
+	}
+	{
+		data, err := c.Export("yaml")
+		assertVal(t, string(data), err, `zeta: 1
+alpha:
+  "y": true
+  b:
+  - q: 1
+    p: 2
+  c: 3
+mid: z
+beta: 2
+`)
+	}
+
+	c, err = NewBuilder().AddJsonFile(jsonFile, false).Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		data, err := c.Export("json")
+		assertVal(t, string(data), err, `{
+  "alpha": {
+    "b": [
+      {
+        "p": 2,
+        "q": 1
+      }
+    ],
+    "y": true
+  },
+  "mid": "x",
+  "zeta": 1
+}`)
+	}
+
+	if _, err = c.Export("xml"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("unsupported format did not return ErrUnsupportedFormat: %v", err)
+	}
+}
+
+func TestExportWrappedProviders(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "config.json")
+	yamlFile := filepath.Join(dir, "override.yaml")
+	writeFile(t, jsonFile, `{"zeta": 1, "alpha": 2, "mid": 3}`)
+	writeFile(t, yamlFile, "omega: 4\nbeta: 5\n")
+
+	c, err := NewBuilder().
+		SetPreserveKeyOrder(true).
+		AddJsonFileTransform(jsonFile, false, func(m ConfigMap) (ConfigMap, error) {
+			return m, nil
+		}).
+		AddYamlFile(yamlFile, false).
+		If(func() bool { return true }).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	data, err := c.Export("json")
+	assertVal(t, string(data), err, `{
+  "zeta": 1,
+  "alpha": 2,
+  "mid": 3,
+  "omega": 4,
+  "beta": 5
+}`)
+}
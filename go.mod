@@ -1,5 +1,11 @@
 module github.com/zekroTJA/configoration
 
-go 1.14
+go 1.20
 
-require gopkg.in/yaml.v2 v2.3.0
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/google/uuid v1.3.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,41 @@
+package configoration
+
+import "strings"
+
+// CompiledKey is a key which is split into its
+// sections once by CompileKey, so that it can be
+// used for repeated lookups without splitting
+// the key on every access.
+type CompiledKey struct {
+	key      string
+	segments []string
+}
+
+// CompileKey splits the passed key by the
+// Delimiter and returns the resulting
+// CompiledKey.
+func CompileKey(key string) CompiledKey {
+	return CompiledKey{
+		key:      key,
+		segments: splitSections(key),
+	}
+}
+
+// CompileKeyWithDelimiter is like CompileKey
+// but splits the passed key by delim instead of
+// the Delimiter. If delim is empty, the
+// Delimiter is used.
+func CompileKeyWithDelimiter(key, delim string) CompiledKey {
+	if delim == "" {
+		return CompileKey(key)
+	}
+	return CompiledKey{
+		key:      key,
+		segments: strings.Split(key, delim),
+	}
+}
+
+// String returns the original key.
+func (ck CompiledKey) String() string {
+	return ck.key
+}
@@ -0,0 +1,115 @@
+package configoration
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompileKey(t *testing.T) {
+	ck := CompileKey("a:b:c")
+	assertSlice(t, ck.segments, nil, []string{"a", "b", "c"})
+	assert(t, ck.String(), "a:b:c")
+}
+
+func TestGetValueCompiled(t *testing.T) {
+	s := makeDefSection()
+
+	{
+		rec, err := s.GetValueCompiled(CompileKey("a:i"))
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetStringCompiled(CompileKey("a:s"))
+		assertVal(t, rec, err, "test123")
+	}
+	{
+		rec, err := s.GetIntCompiled(CompileKey("a:i"))
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetBoolCompiled(CompileKey("a:b"))
+		assertVal(t, rec, err, true)
+	}
+	{
+		rec, err := s.GetFloat64Compiled(CompileKey("a:f"))
+		assertVal(t, rec, err, 3.1415)
+	}
+	{
+		_, err := s.GetIntCompiled(CompileKey("a:s"))
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("conversion error did not match ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetValueCompiled(CompileKey("a:none"))
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || keyErr.Key != "a:none" || !errors.Is(err, ErrNil) {
+			t.Errorf("missing key error (%+v) was not like expected", err)
+		}
+	}
+}
+
+func BenchmarkGetValue(b *testing.B) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"b": ConfigMap{
+				"c": 1,
+			},
+		},
+	})
+
+	b.Run("string", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.GetValue("a:b:c")
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		ck := CompileKey("a:b:c")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.GetValueCompiled(ck)
+		}
+	})
+}
+
+func TestGetValueWithDelimiter(t *testing.T) {
+	s := makeDefSection()
+
+	{
+		rec, err := s.GetValueWithDelimiter("a.i", ".")
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetValueWithDelimiter("a:i", ":")
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetValueWithDelimiter("a:i", "")
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetStringWithDelimiter("a.s", ".")
+		assertVal(t, rec, err, "test123")
+	}
+	{
+		rec, err := s.GetIntWithDelimiter("a/i", "/")
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := s.GetBoolWithDelimiter("a.b", ".")
+		assertVal(t, rec, err, true)
+	}
+	{
+		rec, err := s.GetFloat64WithDelimiter("a.f", ".")
+		assertVal(t, rec, err, 3.1415)
+	}
+	{
+		rec, err := s.WithPrefix("a").GetIntWithDelimiter("i", ".")
+		assertVal(t, rec, err, 1)
+	}
+	if _, err := s.GetValueWithDelimiter("a:i", "."); !errors.Is(err, ErrNil) {
+		t.Errorf("key with other delimiter did not return ErrNil: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package configoration
+
+import "github.com/zekroTJA/configoration/providers"
+
+// resolveLazy decodes v if it is a lazy value.
+// Decoded objects are returned as ConfigMap.
+// Other values are returned as is.
+//
+// Because lazy sources validate the syntax of
+// the whole file on read, decoding errors are
+// not expected and result in a nil value, so
+// callers must check the type of the result.
+func resolveLazy(v interface{}) interface{} {
+	lv, ok := v.(*providers.LazyValue)
+	if !ok {
+		return v
+	}
+
+	dv, err := decodeLazy(lv)
+	if err != nil {
+		return nil
+	}
+	return dv
+}
+
+// decodeLazy decodes lv like resolveLazy but
+// returns decoding errors.
+func decodeLazy(lv *providers.LazyValue) (interface{}, error) {
+	dv, err := lv.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := dv.(map[string]interface{}); ok {
+		return ConfigMap(m), nil
+	}
+	return dv, nil
+}
+
+// isSectionValue returns true if v is a
+// ConfigMap or a lazy object.
+func isSectionValue(v interface{}) bool {
+	switch vt := v.(type) {
+	case ConfigMap:
+		return true
+	case *providers.LazyValue:
+		return vt.IsObject()
+	}
+	return false
+}
+
+// isLazySection returns true if v is a lazy
+// object, whose decoded map is cached by the
+// lazy value and must be copied before it is
+// modified.
+func isLazySection(v interface{}) bool {
+	lv, ok := v.(*providers.LazyValue)
+	return ok && lv.IsObject()
+}
@@ -0,0 +1,74 @@
+package configoration
+
+import (
+	"errors"
+	"strconv"
+)
+
+// IntOverflowPolicy describes how the sized
+// integer getters like GetInt32 handle values
+// exceeding the range of the requested type.
+type IntOverflowPolicy int
+
+const (
+	// OverflowError returns an error matching
+	// ErrInvalidType for values out of range.
+	OverflowError IntOverflowPolicy = iota
+
+	// OverflowSaturate clamps values out of range
+	// to the minimum or maximum of the type.
+	OverflowSaturate
+
+	// OverflowWrap keeps the lower bits of values
+	// out of range like a conversion in Go, so
+	// that 128 read as int8 results in -128.
+	// Values exceeding the range of int64 still
+	// return an error.
+	OverflowWrap
+)
+
+// parseSizedInt parses s as integer which must
+// fit into bitSize according to policy.
+func parseSizedInt(s string, bitSize int, policy IntOverflowPolicy) (int64, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		if policy == OverflowSaturate && errors.Is(err, strconv.ErrRange) {
+			return saturate(v, bitSize), nil
+		}
+		return 0, err
+	}
+
+	minV, maxV := intRange(bitSize)
+	if v >= minV && v <= maxV {
+		return v, nil
+	}
+
+	switch policy {
+	case OverflowSaturate:
+		return saturate(v, bitSize), nil
+	case OverflowWrap:
+		shift := 64 - bitSize
+		return v << shift >> shift, nil
+	}
+	return 0, &strconv.NumError{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+}
+
+// saturate clamps v to the range of a signed
+// integer with bitSize bits.
+func saturate(v int64, bitSize int) int64 {
+	minV, maxV := intRange(bitSize)
+	switch {
+	case v < minV:
+		return minV
+	case v > maxV:
+		return maxV
+	}
+	return v
+}
+
+// intRange returns the minimum and maximum of
+// a signed integer with bitSize bits.
+func intRange(bitSize int) (minV, maxV int64) {
+	minV = int64(-1) << (bitSize - 1)
+	return minV, -(minV + 1)
+}
@@ -0,0 +1,71 @@
+package configoration
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourcePlan describes a source added to the
+// Builder as returned by Plan.
+type SourcePlan struct {
+	// Type is the kind of the source, like
+	// "json" for JSON files, or the type name
+	// of custom providers.
+	Type string
+
+	// Source is the name of the source, which
+	// is the resolved path for file sources.
+	Source string
+
+	// Optional is true if the source may be
+	// absent.
+	Optional bool
+
+	// Conditional is true if the source was
+	// made conditional with If.
+	Conditional bool
+}
+
+// wrappedProvider is implemented by providers
+// which wrap another provider.
+type wrappedProvider interface {
+	unwrap() Provider
+}
+
+// Plan returns a description of all added
+// sources in the order they are merged
+// without reading them.
+func (b *Builder) Plan() []SourcePlan {
+	plan := make([]SourcePlan, len(b.provider))
+	for i, p := range b.provider {
+		plan[i].Source = providerName(p)
+
+		for {
+			if _, ok := p.(*conditionalProvider); ok {
+				plan[i].Conditional = true
+			}
+			wp, ok := p.(wrappedProvider)
+			if !ok {
+				break
+			}
+			p = wp.unwrap()
+		}
+
+		plan[i].Type = providerType(p)
+		plan[i].Optional = isOptional(p)
+	}
+	return plan
+}
+
+// providerType returns the short type name of
+// the providers of the providers package, like
+// "json" for *providers.JsonProvider, or the
+// type name of p.
+func providerType(p Provider) string {
+	name := fmt.Sprintf("%T", p)
+	if !strings.HasPrefix(name, "*providers.") {
+		return name
+	}
+	name = strings.TrimPrefix(name, "*providers.")
+	return strings.ToLower(strings.TrimSuffix(name, "Provider"))
+}
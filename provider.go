@@ -1,5 +1,12 @@
 package configoration
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/zekroTJA/configoration/providers"
+)
+
 // Provider provides functionalities to get
 // a configuration map from a desired source.
 type Provider interface {
@@ -10,3 +17,102 @@ type Provider interface {
 	// returned.
 	GetMap() (map[string]interface{}, error)
 }
+
+// NamedProvider extends the Provider interface
+// with a function to identify the source of the
+// provided configuration map.
+type NamedProvider interface {
+	Provider
+
+	// Name returns an identifier of the source
+	// the provider reads the config values from,
+	// like the file name for file providers.
+	Name() string
+}
+
+// OptionalProvider extends the Provider
+// interface with a function to tell whether
+// the source may be absent.
+type OptionalProvider interface {
+	Provider
+
+	// Optional returns true if the provider does
+	// not fail when its source does not exist.
+	Optional() bool
+}
+
+// FileProvider extends the Provider interface
+// with a function returning the name of the
+// file the values are read from. On reload,
+// file providers are only read again if the
+// modification time or size of the file
+// changed. If FileName returns an empty string,
+// the provider is always read again.
+type FileProvider interface {
+	Provider
+
+	// FileName returns the name of the file the
+	// provider reads the config values from.
+	FileName() string
+}
+
+// OrderedProvider extends the Provider
+// interface with a function returning the order
+// of the keys in the source, which is used by
+// Config.Export.
+type OrderedProvider interface {
+	Provider
+
+	// KeyOrder returns the order of the keys of
+	// the map returned by the last call of
+	// GetMap or nil, if it is unknown.
+	KeyOrder() providers.KeyOrder
+}
+
+// RemoteValue can be returned as value by
+// providers for values which are fetched from
+// a remote source on every read instead of on
+// build.
+type RemoteValue interface {
+	// Fetch returns the current value. It must
+	// respect the deadline and cancellation of
+	// ctx.
+	Fetch(ctx context.Context) (interface{}, error)
+}
+
+// isOptional returns true if p implements
+// OptionalProvider and reports to be optional.
+func isOptional(p Provider) bool {
+	op, ok := p.(OptionalProvider)
+	return ok && op.Optional()
+}
+
+// isFileSource returns true if p, or the
+// provider wrapped by p, reads its values from
+// a file.
+func isFileSource(p Provider) bool {
+	_, ok := unwrapProvider(p).(FileProvider)
+	return ok
+}
+
+// unwrapProvider returns the innermost provider
+// wrapped by p, or p if it wraps no provider.
+func unwrapProvider(p Provider) Provider {
+	for {
+		wp, ok := p.(wrappedProvider)
+		if !ok {
+			return p
+		}
+		p = wp.unwrap()
+	}
+}
+
+// providerName returns the name of p if it
+// implements NamedProvider. Otherwise, the
+// type name of p is returned.
+func providerName(p Provider) string {
+	if np, ok := p.(NamedProvider); ok {
+		return np.Name()
+	}
+	return fmt.Sprintf("%T", p)
+}
@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CsvProvider implements the Provider interface
+// for reading tables from CSV files.
+type CsvProvider struct {
+	fileName string
+	key      string
+	optional bool
+}
+
+// NewCsvProvider produces a new CsvProvider instance
+// with the given fileName, key and optional flag.
+//
+// The first row of the file is used as header. All
+// following rows are mapped to maps keyed by the
+// header columns and are set as a slice under the
+// passed key, which can span over sections like
+// "data:regions". All values are kept as strings.
+func NewCsvProvider(fileName, key string, optional bool) *CsvProvider {
+	return &CsvProvider{
+		fileName: fileName,
+		key:      key,
+		optional: optional,
+	}
+}
+
+func (p *CsvProvider) Name() string {
+	return p.fileName
+}
+
+func (p *CsvProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *CsvProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *CsvProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	rows, err := parseCsv(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", p.fileName, err.Error())
+	}
+
+	m := make(map[string]interface{})
+	ensurePathAndSetValue(m, strings.Split(p.key, keyDelimiter), rows)
+
+	return m, nil
+}
+
+// parseCsv parses the passed CSV data into a
+// slice of maps keyed by the header row.
+func parseCsv(data []byte) ([]interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]interface{}, 0)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(header))
+		for i, col := range header {
+			row[strings.TrimSpace(col)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
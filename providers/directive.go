@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const (
+	// FileDirective is the key of an object which
+	// is replaced by the content of the referenced
+	// file if file directives are enabled.
+	FileDirective = "$file"
+)
+
+// resolveFileDirectives replaces all values of m,
+// its inner maps and slices which are objects
+// with FileDirective as only key by the raw
+// content of the referenced file. Relative file
+// paths are resolved against dir.
+func resolveFileDirectives(m map[string]interface{}, dir string) error {
+	for k, v := range m {
+		rv, err := resolveFileDirective(v, dir)
+		if err != nil {
+			return err
+		}
+		m[k] = rv
+	}
+	return nil
+}
+
+// resolveFileDirective returns the content of the
+// referenced file if v is a file directive object.
+// Otherwise, the file directives within v are
+// resolved and v is returned.
+func resolveFileDirective(v interface{}, dir string) (interface{}, error) {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		if fileName, ok := fileDirectiveTarget(len(vt), vt[FileDirective]); ok {
+			return readDirectiveFile(fileName, dir)
+		}
+		return vt, resolveFileDirectives(vt, dir)
+	case map[interface{}]interface{}:
+		if fileName, ok := fileDirectiveTarget(len(vt), vt[FileDirective]); ok {
+			return readDirectiveFile(fileName, dir)
+		}
+		for k, e := range vt {
+			re, err := resolveFileDirective(e, dir)
+			if err != nil {
+				return nil, err
+			}
+			vt[k] = re
+		}
+	case []interface{}:
+		for i, e := range vt {
+			re, err := resolveFileDirective(e, dir)
+			if err != nil {
+				return nil, err
+			}
+			vt[i] = re
+		}
+	}
+	return v, nil
+}
+
+// fileDirectiveTarget returns the file name of a
+// file directive object with size entries and
+// the value target of the FileDirective key.
+func fileDirectiveTarget(size int, target interface{}) (string, bool) {
+	fileName, ok := target.(string)
+	return fileName, ok && size == 1
+}
+
+// readDirectiveFile returns the content of the
+// passed file resolved against dir.
+func readDirectiveFile(fileName, dir string) (string, error) {
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(dir, fileName)
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", FileDirective, err.Error())
+	}
+	return string(data), nil
+}
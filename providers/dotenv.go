@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DotEnvProvider implements the Provider interface
+// for reading .env files.
+type DotEnvProvider struct {
+	fileName  string
+	optional  bool
+	lowercase bool
+}
+
+// NewDotEnvProvider produces a new DotEnvProvider
+// instance with the given fileName and optional
+// flag.
+//
+// Each line contains a KEY=value pair, optionally
+// prefixed with "export". Values may be wrapped
+// in single or double quotes. Lines starting
+// with "#" are ignored, as are comments after
+// unquoted values. Keys are split by "__" into
+// nested sections like for environment
+// variables.
+func NewDotEnvProvider(fileName string, optional bool) *DotEnvProvider {
+	return &DotEnvProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+// SetLowercase sets whether keys should be
+// converted to lowercase.
+func (p *DotEnvProvider) SetLowercase(lowercase bool) *DotEnvProvider {
+	p.lowercase = lowercase
+	return p
+}
+
+func (p *DotEnvProvider) Name() string {
+	return p.fileName
+}
+
+func (p *DotEnvProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *DotEnvProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *DotEnvProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	return parseDotEnv(p.fileName, data, p.lowercase)
+}
+
+// parseDotEnv parses the KEY=value pairs of
+// data. source is used in errors.
+func parseDotEnv(source string, data []byte, lowercase bool) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: invalid line", source, n)
+		}
+
+		if lowercase {
+			key = strings.ToLower(key)
+		}
+		ensurePathAndSetValue(m, strings.Split(key, envDelimiter), dotEnvValue(val))
+	}
+
+	return m, scanner.Err()
+}
+
+// dotEnvValue returns the unquoted value of v.
+// Comments after unquoted values are removed.
+func dotEnvValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) > 1 && (v[0] == '"' || v[0] == '\'') {
+		if end := strings.IndexByte(v[1:], v[0]); end >= 0 {
+			return v[1 : end+1]
+		}
+	}
+
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v
+}
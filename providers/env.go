@@ -1,50 +1,160 @@
 package providers
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 const (
-	envDelimiter = "__"
+	envDelimiter  = "__"
+	envFileSuffix = "_FILE"
 )
 
 // EnvProvider implements the Provider interface for
 // environment variables as configuration providers.
 type EnvProvider struct {
-	prefix    string
-	lowercase bool
+	prefix      string
+	lowercase   bool
+	fileSecrets bool
+	skipEmpty   bool
+	foldPrefix  bool
+	indexed     bool
+	section     string
+	separator   string
+}
+
+// EnvOption configures optional behavior of
+// an EnvProvider.
+type EnvOption func(p *EnvProvider)
+
+// WithFileSecrets enables reading values from
+// files referenced by variables ending with
+// "_FILE", like DB_PASSWORD_FILE=/run/secrets/db.
+// The content of the file, without trailing
+// line breaks, is set as value of the key
+// without the suffix. If the variable without
+// the suffix is set as well, it takes
+// precedence.
+func WithFileSecrets() EnvOption {
+	return func(p *EnvProvider) {
+		p.fileSecrets = true
+	}
+}
+
+// TreatEmptyEnvAsUnset skips variables with an
+// empty value, like FOO=, so that they do not
+// override values of previous sources.
+func TreatEmptyEnvAsUnset() EnvOption {
+	return func(p *EnvProvider) {
+		p.skipEmpty = true
+	}
+}
+
+// WithPrefixCaseFold matches the prefix case
+// insensitively, so that both TEST_FOO and
+// test_foo are read with the prefix "TEST_".
+// The remainder of the variable name is
+// processed like for other variables.
+func WithPrefixCaseFold() EnvOption {
+	return func(p *EnvProvider) {
+		p.foldPrefix = true
+	}
+}
+
+// WithIndexedArrays collapses variables whose
+// names end with "_" followed by an index, like
+// ITEM_0 and ITEM_1, into a slice set for the
+// name without the suffix, ordered by index.
+// Gaps between indices are dropped, so ITEM_0
+// and ITEM_5 result in a slice of two elements.
+// If a variable with the name without the
+// suffix is set as well, the indexed variables
+// are kept as they are.
+func WithIndexedArrays() EnvOption {
+	return func(p *EnvProvider) {
+		p.indexed = true
+	}
+}
+
+// IntoSection sets all variables below the
+// section with the passed name instead of the
+// root, so that TEST_PORT is read as
+// "env:port" with IntoSection("env"). Nested
+// sections can be selected with ":" like
+// "sources:env".
+func IntoSection(name string) EnvOption {
+	return func(p *EnvProvider) {
+		p.section = name
+	}
+}
+
+// WithNestingSeparator sets the separator of
+// sections in variable names, which is "__" by
+// default. Only the separator splits sections,
+// so single underscores are always kept as part
+// of a key, like DB__CONNECTION_STRING which is
+// read as "db:connection_string". If keys
+// contain "__" themselves, a separator which is
+// not used in keys, like "___", can be set.
+// An empty separator disables nesting.
+func WithNestingSeparator(sep string) EnvOption {
+	return func(p *EnvProvider) {
+		p.separator = sep
+	}
 }
 
 // NewEnvProvider returns a new instance of EnvProvider
 // with the passed prefix and lowercase specification.
-func NewEnvProvider(prefix string, lowercase bool) *EnvProvider {
-	return &EnvProvider{
+func NewEnvProvider(prefix string, lowercase bool, opts ...EnvOption) *EnvProvider {
+	p := &EnvProvider{
 		prefix:    prefix,
 		lowercase: lowercase,
+		separator: envDelimiter,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
-func (p *EnvProvider) GetMap() (map[string]interface{}, error) {
-	environ := os.Environ()
-	env := make(map[string]interface{})
+func (p *EnvProvider) Name() string {
+	return "env:" + p.prefix
+}
 
-	for _, e := range environ {
-		if !strings.HasPrefix(e, p.prefix) {
+func (p *EnvProvider) GetMap() (map[string]interface{}, error) {
+	vars := make(map[string]string)
+	for _, e := range os.Environ() {
+		if !p.hasPrefix(e) {
 			continue
 		}
 
-		e = e[len(p.prefix):]
+		kvSplit := strings.SplitN(e[len(p.prefix):], "=", 2)
+		if p.skipEmpty && kvSplit[1] == "" {
+			continue
+		}
+		vars[kvSplit[0]] = kvSplit[1]
+	}
 
-		kvSplit := strings.SplitN(e, "=", 2)
-		key := kvSplit[0]
-		val := kvSplit[1]
+	if p.fileSecrets {
+		if err := readFileSecrets(vars); err != nil {
+			return nil, err
+		}
+	}
 
+	env := make(map[string]interface{})
+	for key, val := range vars {
 		if p.lowercase {
 			key = strings.ToLower(key)
 		}
 
-		sections := strings.Split(key, envDelimiter)
+		var sections []string
+		if p.separator != "" {
+			sections = strings.Split(key, p.separator)
+		}
 		if len(sections) > 1 {
 			ensurePathAndSetValue(env, sections, val)
 		} else {
@@ -52,16 +162,119 @@ func (p *EnvProvider) GetMap() (map[string]interface{}, error) {
 		}
 	}
 
+	if p.indexed {
+		collapseIndexedKeys(env)
+	}
+
+	if p.section != "" && len(env) > 0 {
+		res := make(map[string]interface{})
+		ensurePathAndSetValue(res, strings.Split(p.section, keyDelimiter), env)
+		return res, nil
+	}
+
 	return env, nil
 }
 
+// collapseIndexedKeys replaces all keys of m and
+// its inner maps ending with "_" and an index by
+// a slice set for the key without the suffix.
+func collapseIndexedKeys(m map[string]interface{}) {
+	type element struct {
+		key   string
+		index int
+	}
+
+	indexed := make(map[string][]element)
+	for k, v := range m {
+		if vm, ok := v.(map[string]interface{}); ok {
+			collapseIndexedKeys(vm)
+		}
+
+		i := strings.LastIndexByte(k, '_')
+		if i <= 0 || !isDigits(k[i+1:]) {
+			continue
+		}
+		idx, err := strconv.Atoi(k[i+1:])
+		if err != nil {
+			continue
+		}
+		indexed[k[:i]] = append(indexed[k[:i]], element{key: k, index: idx})
+	}
+
+	for base, elems := range indexed {
+		if _, ok := m[base]; ok {
+			continue
+		}
+		sort.Slice(elems, func(i, j int) bool {
+			return elems[i].index < elems[j].index
+		})
+		vals := make([]interface{}, len(elems))
+		for i, e := range elems {
+			vals[i] = m[e.key]
+			delete(m, e.key)
+		}
+		m[base] = vals
+	}
+}
+
+// isDigits returns true if s is not empty and
+// only contains the digits 0 to 9.
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// hasPrefix returns true if the variable e
+// starts with the prefix of p.
+func (p *EnvProvider) hasPrefix(e string) bool {
+	if p.foldPrefix {
+		return len(e) >= len(p.prefix) && strings.EqualFold(e[:len(p.prefix)], p.prefix)
+	}
+	return strings.HasPrefix(e, p.prefix)
+}
+
+// readFileSecrets replaces all variables in vars
+// ending with the file suffix by the content of
+// the referenced file set for the variable name
+// without the suffix, if not already set.
+func readFileSecrets(vars map[string]string) error {
+	files := make(map[string]string)
+	for key, fileName := range vars {
+		if strings.HasSuffix(key, envFileSuffix) {
+			files[key] = fileName
+			delete(vars, key)
+		}
+	}
+
+	for fileKey, fileName := range files {
+		key := fileKey[:len(fileKey)-len(envFileSuffix)]
+		if _, ok := vars[key]; ok {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fileKey, err.Error())
+		}
+		vars[key] = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return nil
+}
+
 func ensurePathAndSetValue(m map[string]interface{}, sections []string, val interface{}) {
 	for i := 0; i < len(sections)-1; i++ {
 		sec := sections[i]
-		if _, ok := m[sec]; !ok {
-			m[sec] = make(map[string]interface{})
+		next, ok := m[sec].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[sec] = next
 		}
-		m = m[sec].(map[string]interface{})
+		m = next
 	}
 
 	m[sections[len(sections)-1]] = val
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"unicode/utf16"
+)
+
+var (
+	bomUtf8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUtf16LE = []byte{0xFF, 0xFE}
+	bomUtf16BE = []byte{0xFE, 0xFF}
+)
+
+// readFile reads the content of the passed file.
+// If the file does not exist and optional is set,
+// ok is false and no error is returned.
+//
+// Content starting with a byte order mark is
+// returned as UTF-8 without the mark.
+func readFile(fileName string, optional bool) (data []byte, ok bool, err error) {
+	_, err = os.Stat(fileName)
+	if err != nil {
+		if os.IsNotExist(err) && optional {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	data, err = ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return decodeBOM(data), true, nil
+}
+
+// decodeBOM strips a leading UTF-8 byte order
+// mark from data. UTF-16 content with a byte
+// order mark is converted to UTF-8. Content
+// without a byte order mark is returned as is.
+func decodeBOM(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, bomUtf8):
+		return data[len(bomUtf8):]
+	case bytes.HasPrefix(data, bomUtf16LE):
+		return utf16ToUtf8(data[len(bomUtf16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUtf16BE):
+		return utf16ToUtf8(data[len(bomUtf16BE):], binary.BigEndian)
+	}
+	return data
+}
+
+// utf16ToUtf8 converts the UTF-16 encoded data
+// with the passed byte order to UTF-8. A
+// trailing odd byte is dropped.
+func utf16ToUtf8(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// isBlank returns true if data is empty or only
+// contains whitespace.
+func isBlank(data []byte) bool {
+	return len(bytes.TrimSpace(data)) == 0
+}
@@ -0,0 +1,28 @@
+package providers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// InferType tries to parse the passed string
+// value as int, float64 or bool (only "true"
+// and "false", case insensitive) in this order
+// and returns the first successfully parsed
+// value. If no type matches, v is returned
+// as string.
+func InferType(v string) interface{} {
+	if vi, err := strconv.Atoi(v); err == nil {
+		return vi
+	}
+	if vf, err := strconv.ParseFloat(v, 64); err == nil {
+		return vf
+	}
+	if strings.EqualFold(v, "true") {
+		return true
+	}
+	if strings.EqualFold(v, "false") {
+		return false
+	}
+	return v
+}
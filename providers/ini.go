@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// IniProvider implements the Provider interface
+// for reading INI config files.
+type IniProvider struct {
+	fileName string
+	optional bool
+}
+
+// NewIniProvider produces a new IniProvider instance
+// with the given fileName and optional flag.
+//
+// Each [section] is mapped to a sub-section and
+// key=value entries are set as values within it.
+// Entries before the first section header are set
+// on the root level. Lines starting with ";" or
+// "#" are ignored.
+func NewIniProvider(fileName string, optional bool) *IniProvider {
+	return &IniProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+func (p *IniProvider) Name() string {
+	return p.fileName
+}
+
+func (p *IniProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *IniProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *IniProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	m, err := parseIni(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", p.fileName, err.Error())
+	}
+
+	return m, nil
+}
+
+// parseIni parses the passed INI data into
+// a map with one inner map per section.
+func parseIni(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: invalid section header", lineNum)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			sec, ok := root[name].(map[string]interface{})
+			if !ok {
+				sec = make(map[string]interface{})
+				root[name] = sec
+			}
+			current = sec
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("line %d: invalid entry", lineNum)
+		}
+		current[strings.TrimSpace(kv[0])] = unquote(strings.TrimSpace(kv[1]))
+	}
+
+	return root, scanner.Err()
+}
+
+// unquote removes surrounding double or single
+// quotes from v, if existent.
+func unquote(v string) string {
+	if len(v) > 1 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
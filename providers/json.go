@@ -1,15 +1,21 @@
 package providers
 
 import (
+	"bytes"
 	"encoding/json"
-	"os"
+	"path/filepath"
 )
 
 // JsonProvider implements the Provider interface
 // for reading JSON config files.
 type JsonProvider struct {
-	fileName string
-	optional bool
+	fileName       string
+	optional       bool
+	strict         bool
+	allowEmpty     bool
+	fileDirectives bool
+	keyOrder       bool
+	order          KeyOrder
 }
 
 // NewJsonProvider produces a new YamlProvider instance
@@ -21,23 +27,104 @@ func NewJsonProvider(fileName string, optional bool) *JsonProvider {
 	}
 }
 
+// SetStrict sets whether the file should be
+// checked for duplicate keys before decoding.
+// If a duplicate key is found, GetMap returns
+// a *DuplicateKeyError.
+func (p *JsonProvider) SetStrict(strict bool) *JsonProvider {
+	p.strict = strict
+	return p
+}
+
+// SetAllowEmpty sets whether an existing but
+// empty file, which only contains whitespace, is
+// read as an empty config instead of failing to
+// parse. This also applies if the provider is
+// not optional.
+func (p *JsonProvider) SetAllowEmpty(allowEmpty bool) *JsonProvider {
+	p.allowEmpty = allowEmpty
+	return p
+}
+
+// SetFileDirectives sets whether objects which
+// only consist of the key "$file" are replaced
+// by the raw content of the referenced file.
+// Relative paths are resolved against the
+// directory of the including file.
+func (p *JsonProvider) SetFileDirectives(enabled bool) *JsonProvider {
+	p.fileDirectives = enabled
+	return p
+}
+
+// SetKeyOrder sets whether the order of the
+// keys in the file is recorded on GetMap, so
+// that it can be read with KeyOrder.
+func (p *JsonProvider) SetKeyOrder(enabled bool) *JsonProvider {
+	p.keyOrder = enabled
+	return p
+}
+
+// KeyOrder returns the order of the keys read
+// by the last call of GetMap, if recording the
+// key order is enabled.
+func (p *JsonProvider) KeyOrder() KeyOrder {
+	return p.order
+}
+
+func (p *JsonProvider) Name() string {
+	return p.fileName
+}
+
+func (p *JsonProvider) Optional() bool {
+	return p.optional
+}
+
+// FileName returns the name of the read file.
+// If file directives are enabled, an empty
+// string is returned, as the values also depend
+// on the referenced files.
+func (p *JsonProvider) FileName() string {
+	if p.fileDirectives {
+		return ""
+	}
+	return p.fileName
+}
+
 func (p *JsonProvider) GetMap() (map[string]interface{}, error) {
-	_, err := os.Stat(p.fileName)
-	if err != nil {
-		if os.IsNotExist(err) && p.optional {
-			return nil, nil
-		}
+	p.order = nil
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
 		return nil, err
 	}
+	if p.allowEmpty && isBlank(data) {
+		return nil, nil
+	}
 
-	f, err := os.Open(p.fileName)
-	if err != nil {
-		return nil, err
+	if p.strict {
+		dup, err := findJsonDuplicate(data)
+		if err != nil {
+			return nil, err
+		}
+		if dup != "" {
+			return nil, &DuplicateKeyError{File: p.fileName, Key: dup}
+		}
 	}
 
 	m := make(map[string]interface{})
-	dec := json.NewDecoder(f)
-	err = dec.Decode(&m)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&m); err != nil {
+		return m, err
+	}
+
+	if p.keyOrder {
+		if p.order, err = jsonKeyOrder(data); err != nil {
+			return m, err
+		}
+	}
+
+	if p.fileDirectives {
+		err = resolveFileDirectives(m, filepath.Dir(p.fileName))
+	}
 
 	return m, err
 }
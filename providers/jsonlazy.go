@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// LazyValue is a JSON object or array of a
+// LazyJsonProvider which is decoded on first
+// access.
+type LazyValue struct {
+	raw json.RawMessage
+
+	once sync.Once
+	v    interface{}
+	err  error
+}
+
+// IsObject returns true if the value is
+// a JSON object.
+func (l *LazyValue) IsObject() bool {
+	return isJsonObject(l.raw)
+}
+
+// Raw returns the undecoded JSON data of
+// the value.
+func (l *LazyValue) Raw() []byte {
+	return l.raw
+}
+
+// Decode decodes the value on first call and
+// returns the cached result on all subsequent
+// calls.
+//
+// Objects are decoded to map[string]interface{}
+// containing *LazyValue instances for inner
+// objects and arrays. Arrays are decoded
+// completely to []interface{}.
+func (l *LazyValue) Decode() (interface{}, error) {
+	l.once.Do(func() {
+		if !l.IsObject() {
+			l.err = json.Unmarshal(l.raw, &l.v)
+			return
+		}
+
+		var rm map[string]json.RawMessage
+		if l.err = json.Unmarshal(l.raw, &rm); l.err != nil {
+			return
+		}
+		l.v, l.err = lazyMap(rm)
+	})
+	return l.v, l.err
+}
+
+// LazyJsonProvider implements the Provider interface
+// for reading JSON config files which are only
+// decoded on the top level when read. Inner objects
+// and arrays are kept as *LazyValue and are decoded
+// on first access.
+type LazyJsonProvider struct {
+	fileName   string
+	optional   bool
+	allowEmpty bool
+}
+
+// NewLazyJsonProvider produces a new LazyJsonProvider
+// instance with the given fileName and optional flag.
+func NewLazyJsonProvider(fileName string, optional bool) *LazyJsonProvider {
+	return &LazyJsonProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+// SetAllowEmpty sets whether an existing but
+// empty file, which only contains whitespace, is
+// read as an empty config instead of failing to
+// parse. This also applies if the provider is
+// not optional.
+func (p *LazyJsonProvider) SetAllowEmpty(allowEmpty bool) *LazyJsonProvider {
+	p.allowEmpty = allowEmpty
+	return p
+}
+
+func (p *LazyJsonProvider) Name() string {
+	return p.fileName
+}
+
+func (p *LazyJsonProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *LazyJsonProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *LazyJsonProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+	if p.allowEmpty && isBlank(data) {
+		return nil, nil
+	}
+
+	var rm map[string]json.RawMessage
+	if err = json.Unmarshal(data, &rm); err != nil {
+		return nil, err
+	}
+
+	return lazyMap(rm)
+}
+
+// lazyMap decodes all scalar values of rm and
+// wraps objects and arrays into *LazyValue.
+func lazyMap(rm map[string]json.RawMessage) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(rm))
+	for k, raw := range rm {
+		if isJsonObject(raw) || isJsonArray(raw) {
+			m[k] = &LazyValue{raw: raw}
+			continue
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func isJsonObject(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '{'
+}
+
+func isJsonArray(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '['
+}
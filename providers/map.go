@@ -0,0 +1,23 @@
+package providers
+
+// MapProvider implements the Provider interface
+// for reading config values from a map.
+type MapProvider struct {
+	m map[string]interface{}
+}
+
+// NewMapProvider produces a new MapProvider
+// instance with the given config map.
+func NewMapProvider(m map[string]interface{}) *MapProvider {
+	return &MapProvider{
+		m: m,
+	}
+}
+
+func (p *MapProvider) Name() string {
+	return "map"
+}
+
+func (p *MapProvider) GetMap() (map[string]interface{}, error) {
+	return p.m, nil
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// KeyOrder maps the path of each section, with
+// the keys of the path joined by ":", to its
+// keys in the order they are defined in the
+// source. The root section has the empty path.
+type KeyOrder map[string][]string
+
+// jsonKeyOrder walks the passed JSON data token
+// by token and returns the order of its keys.
+func jsonKeyOrder(data []byte) (KeyOrder, error) {
+	order := make(KeyOrder)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	return order, walkJsonOrder(dec, "", order)
+}
+
+func walkJsonOrder(dec *json.Decoder, path string, order KeyOrder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := kt.(string)
+			order[path] = append(order[path], key)
+			if err = walkJsonOrder(dec, joinKey(path, key), order); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := walkJsonOrder(dec, joinKey(path, strconv.Itoa(i)), order); err != nil {
+				return err
+			}
+		}
+	}
+
+	// consume the closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+// yamlKeyOrder decodes the passed YAML data into
+// an order preserving yaml.MapSlice and returns
+// the order of its keys.
+func yamlKeyOrder(data []byte) (KeyOrder, error) {
+	var ms yaml.MapSlice
+	if err := yaml.Unmarshal(data, &ms); err != nil {
+		return nil, err
+	}
+	order := make(KeyOrder)
+	walkYamlOrder(ms, "", order)
+	return order, nil
+}
+
+func walkYamlOrder(v interface{}, path string, order KeyOrder) {
+	switch vt := v.(type) {
+	case yaml.MapSlice:
+		for _, item := range vt {
+			key := fmt.Sprintf("%v", item.Key)
+			order[path] = append(order[path], key)
+			walkYamlOrder(item.Value, joinKey(path, key), order)
+		}
+	case []interface{}:
+		for i, e := range vt {
+			walkYamlOrder(e, joinKey(path, strconv.Itoa(i)), order)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// PropertiesProvider implements the Provider interface
+// for reading Java .properties config files.
+type PropertiesProvider struct {
+	fileName string
+	optional bool
+}
+
+// NewPropertiesProvider produces a new PropertiesProvider
+// instance with the given fileName and optional flag.
+//
+// Dotted keys like "db.pool.size" are expanded into
+// nested sections. Lines ending with a "\" are
+// continued on the next line and lines starting
+// with "#" or "!" are ignored.
+func NewPropertiesProvider(fileName string, optional bool) *PropertiesProvider {
+	return &PropertiesProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+func (p *PropertiesProvider) Name() string {
+	return p.fileName
+}
+
+func (p *PropertiesProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *PropertiesProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *PropertiesProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		if endsWithContinuation(line) {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+
+		logical.WriteString(line)
+		key, val := splitProperty(logical.String())
+		logical.Reset()
+
+		ensurePathAndSetValue(m, strings.Split(key, "."), val)
+	}
+
+	if logical.Len() > 0 {
+		key, val := splitProperty(logical.String())
+		ensurePathAndSetValue(m, strings.Split(key, "."), val)
+	}
+
+	return m, scanner.Err()
+}
+
+// endsWithContinuation returns true if line ends
+// with an odd number of backslashes.
+func endsWithContinuation(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits the passed logical line at
+// the first unescaped "=", ":" or whitespace into
+// the unescaped key and value.
+func splitProperty(line string) (string, string) {
+	sep := len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			sep = i
+			break
+		}
+	}
+
+	key := line[:sep]
+	rest := strings.TrimLeft(line[sep:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	return unescapeProperty(key), unescapeProperty(rest)
+}
+
+// unescapeProperty resolves backslash escape
+// sequences in the passed key or value.
+func unescapeProperty(v string) string {
+	if !strings.Contains(v, "\\") {
+		return v
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c != '\\' || i == len(v)-1 {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch v[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		default:
+			sb.WriteByte(v[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// RawProvider implements the Provider interface
+// for reading config values from data in one of
+// the formats json, yaml, toml or env.
+type RawProvider struct {
+	format   string
+	data     []byte
+	optional bool
+	yaml12   bool
+}
+
+// NewRawProvider produces a new RawProvider
+// instance with the given format, data and
+// optional flag.
+func NewRawProvider(format string, data []byte, optional bool) *RawProvider {
+	return &RawProvider{
+		format:   format,
+		data:     data,
+		optional: optional,
+	}
+}
+
+// SetYaml12Booleans sets whether YAML data only
+// decodes the YAML 1.2 literals true and false
+// as booleans.
+func (p *RawProvider) SetYaml12Booleans(enabled bool) *RawProvider {
+	p.yaml12 = enabled
+	return p
+}
+
+func (p *RawProvider) Name() string {
+	return "raw " + p.format
+}
+
+func (p *RawProvider) Optional() bool {
+	return p.optional
+}
+
+// GetMap parses the data in the set format. If
+// the provider is optional, empty data is read
+// as empty config.
+func (p *RawProvider) GetMap() (map[string]interface{}, error) {
+	data := decodeBOM(p.data)
+	if p.optional && len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	return p.decode(data)
+}
+
+func (p *RawProvider) decode(data []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	switch p.format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		return (&YamlProvider{yaml12: p.yaml12}).decode(data)
+	case "toml":
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+	case "env":
+		return parseDotEnv(p.Name(), data, false)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", p.format)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"strings"
+)
+
+// RedisHashClient describes a Redis client which
+// is able to read all fields of a hash.
+//
+// Clients like go-redis can be adapted by calling
+// HGetAll(ctx, key).Result().
+type RedisHashClient interface {
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+}
+
+// RedisHashProvider implements the Provider interface
+// for reading config values from a Redis hash.
+type RedisHashProvider struct {
+	client     RedisHashClient
+	key        string
+	optional   bool
+	inferTypes bool
+}
+
+// NewRedisHashProvider produces a new RedisHashProvider
+// instance with the given client, hash key and
+// optional flag.
+//
+// Hash fields containing ":" are expanded into
+// nested sections.
+func NewRedisHashProvider(client RedisHashClient, key string, optional bool) *RedisHashProvider {
+	return &RedisHashProvider{
+		client:   client,
+		key:      key,
+		optional: optional,
+	}
+}
+
+// SetInferTypes sets whether the string values
+// of the hash fields should be parsed to int,
+// float64 or bool using InferType.
+func (p *RedisHashProvider) SetInferTypes(inferTypes bool) *RedisHashProvider {
+	p.inferTypes = inferTypes
+	return p
+}
+
+func (p *RedisHashProvider) Name() string {
+	return "redis:" + p.key
+}
+
+func (p *RedisHashProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *RedisHashProvider) GetMap() (map[string]interface{}, error) {
+	hash, err := p.client.HGetAll(context.Background(), p.key)
+	if err != nil {
+		if p.optional {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	for field, v := range hash {
+		var val interface{} = v
+		if p.inferTypes {
+			val = InferType(v)
+		}
+		ensurePathAndSetValue(m, strings.Split(field, keyDelimiter), val)
+	}
+
+	return m, nil
+}
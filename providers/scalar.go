@@ -0,0 +1,70 @@
+package providers
+
+import "strings"
+
+// ScalarFileProvider implements the Provider
+// interface for reading the content of a file
+// as a single value.
+type ScalarFileProvider struct {
+	fileName   string
+	key        string
+	optional   bool
+	inferTypes bool
+}
+
+// NewScalarFileProvider produces a new
+// ScalarFileProvider instance with the given
+// fileName, key and optional flag.
+//
+// The content of the file, without trailing
+// line breaks, is set as string value of the
+// passed key, which can span over sections
+// like "db:password".
+func NewScalarFileProvider(fileName, key string, optional bool) *ScalarFileProvider {
+	return &ScalarFileProvider{
+		fileName: fileName,
+		key:      key,
+		optional: optional,
+	}
+}
+
+// SetInferTypes sets whether the content of the
+// file should be parsed to int, float64 or bool
+// using InferType. Surrounding quotes are
+// removed before, so that a file containing
+// the JSON string "hello" is read as hello.
+func (p *ScalarFileProvider) SetInferTypes(inferTypes bool) *ScalarFileProvider {
+	p.inferTypes = inferTypes
+	return p
+}
+
+func (p *ScalarFileProvider) Name() string {
+	return p.fileName
+}
+
+func (p *ScalarFileProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *ScalarFileProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *ScalarFileProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	v := strings.TrimRight(string(data), "\r\n")
+
+	var val interface{} = v
+	if p.inferTypes {
+		val = InferType(unquote(strings.TrimSpace(v)))
+	}
+
+	m := make(map[string]interface{})
+	ensurePathAndSetValue(m, strings.Split(p.key, keyDelimiter), val)
+
+	return m, nil
+}
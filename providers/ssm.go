@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+	"strings"
+)
+
+// SSMParameter is a single parameter read from
+// the AWS SSM Parameter Store.
+type SSMParameter struct {
+	// Name is the full, "/" delimited name of
+	// the parameter.
+	Name string
+
+	// Value is the value of the parameter.
+	Value string
+
+	// Secure is true if the parameter is of
+	// type SecureString.
+	Secure bool
+}
+
+// SSMClient describes an AWS SSM client which
+// is able to read one page of parameters below
+// a path.
+//
+// Clients like the ssm.Client of aws-sdk-go-v2
+// can be adapted by calling GetParametersByPath
+// with the passed values and returning the
+// parameters and NextToken of the output.
+type SSMClient interface {
+	GetParametersByPath(ctx context.Context, path string, recursive, decrypt bool,
+		nextToken string) (params []SSMParameter, next string, err error)
+}
+
+// SSMProvider implements the Provider interface
+// for reading config values from a path of the
+// AWS SSM Parameter Store.
+type SSMProvider struct {
+	client     SSMClient
+	path       string
+	optional   bool
+	decrypt    bool
+	secretKeys []string
+}
+
+// NewSSMProvider produces a new SSMProvider
+// instance with the given client, parameter
+// path, optional and decrypt flag.
+//
+// All parameters below path are read
+// recursively and their names relative to path
+// are split by "/" into nested sections. If
+// decrypt is set, SecureString values are
+// decrypted.
+func NewSSMProvider(client SSMClient, path string, optional, decrypt bool) *SSMProvider {
+	return &SSMProvider{
+		client:   client,
+		path:     path,
+		optional: optional,
+		decrypt:  decrypt,
+	}
+}
+
+func (p *SSMProvider) Name() string {
+	return "ssm:" + p.path
+}
+
+func (p *SSMProvider) Optional() bool {
+	return p.optional
+}
+
+// SecretKeys returns the ":" delimited keys of
+// all SecureString parameters read by the last
+// call to GetMap, for example to redact them
+// when logging the config.
+func (p *SSMProvider) SecretKeys() []string {
+	return p.secretKeys
+}
+
+func (p *SSMProvider) GetMap() (map[string]interface{}, error) {
+	var params []SSMParameter
+	var next string
+	for {
+		page, n, err := p.client.GetParametersByPath(
+			context.Background(), p.path, true, p.decrypt, next)
+		if err != nil {
+			if p.optional {
+				return nil, nil
+			}
+			return nil, err
+		}
+		params = append(params, page...)
+		if n == "" {
+			break
+		}
+		next = n
+	}
+
+	m := make(map[string]interface{})
+	p.secretKeys = p.secretKeys[:0]
+	prefix := strings.TrimSuffix(p.path, "/") + "/"
+	for _, param := range params {
+		name := strings.Trim(strings.TrimPrefix(param.Name, prefix), "/")
+		if name == "" {
+			continue
+		}
+		sections := strings.Split(name, "/")
+		ensurePathAndSetValue(m, sections, param.Value)
+		if param.Secure {
+			p.secretKeys = append(p.secretKeys, strings.Join(sections, keyDelimiter))
+		}
+	}
+
+	return m, nil
+}
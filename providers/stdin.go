@@ -0,0 +1,109 @@
+package providers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+var (
+	// ErrEmptyStdin is returned by the
+	// StdinProvider when it is not optional and
+	// no data was read from stdin.
+	ErrEmptyStdin = errors.New("stdin is empty")
+)
+
+// OnceReader reads all data of a reader, like
+// os.Stdin, on the first call of ReadAll and
+// returns the same data on all following calls,
+// so that it can be shared by multiple
+// providers.
+type OnceReader struct {
+	r io.Reader
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// NewOnceReader produces a new OnceReader
+// instance reading r.
+func NewOnceReader(r io.Reader) *OnceReader {
+	return &OnceReader{r: r}
+}
+
+// ReadAll returns all data of the reader, which
+// is only read on the first call.
+func (r *OnceReader) ReadAll() ([]byte, error) {
+	r.once.Do(func() {
+		r.data, r.err = ioutil.ReadAll(r.r)
+	})
+	return r.data, r.err
+}
+
+// StdinProvider implements the Provider interface
+// for reading config values piped to stdin in
+// one of the formats json, yaml, toml or env.
+//
+// Stdin is read through a OnceReader, so that
+// the data is reused on subsequent calls of
+// GetMap, like on reloads, and by all providers
+// sharing the reader.
+type StdinProvider struct {
+	format   string
+	r        *OnceReader
+	optional bool
+	yaml12   bool
+}
+
+// NewStdinProvider produces a new StdinProvider
+// instance with the given format, reader and
+// optional flag. All providers reading the same
+// stream must share the reader.
+func NewStdinProvider(format string, r *OnceReader, optional bool) *StdinProvider {
+	return &StdinProvider{
+		format:   format,
+		r:        r,
+		optional: optional,
+	}
+}
+
+// SetYaml12Booleans sets whether YAML data only
+// decodes the YAML 1.2 literals true and false
+// as booleans.
+func (p *StdinProvider) SetYaml12Booleans(enabled bool) *StdinProvider {
+	p.yaml12 = enabled
+	return p
+}
+
+func (p *StdinProvider) Name() string {
+	return "stdin"
+}
+
+func (p *StdinProvider) Optional() bool {
+	return p.optional
+}
+
+// GetMap parses the data read from stdin in the
+// set format. If no data or only whitespace was
+// read, nil is returned if the provider is
+// optional and ErrEmptyStdin otherwise.
+func (p *StdinProvider) GetMap() (map[string]interface{}, error) {
+	data, err := p.r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(decodeBOM(data))) == 0 {
+		if p.optional {
+			return nil, nil
+		}
+		return nil, ErrEmptyStdin
+	}
+
+	raw := NewRawProvider(p.format, data, false).
+		SetYaml12Booleans(p.yaml12)
+	return raw.GetMap()
+}
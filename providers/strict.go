@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	keyDelimiter = ":"
+)
+
+// DuplicateKeyError is returned by file providers
+// in strict mode when a key is defined more than
+// once on the same level of a single file.
+type DuplicateKeyError struct {
+	// File is the name of the file which
+	// contains the duplicate key.
+	File string
+
+	// Key is the full path of the duplicate
+	// key separated by ":".
+	Key string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%s: duplicate key %q", e.File, e.Key)
+}
+
+// findJsonDuplicate walks the passed JSON data
+// token by token and returns the path of the
+// first duplicate key found. If no duplicate
+// key exists, an empty string is returned.
+func findJsonDuplicate(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	return walkJsonValue(dec, "")
+}
+
+func walkJsonValue(dec *json.Decoder, path string) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return "", nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]struct{})
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return "", err
+			}
+			key, _ := kt.(string)
+			keyPath := joinKey(path, key)
+			if _, ok := seen[key]; ok {
+				return keyPath, nil
+			}
+			seen[key] = struct{}{}
+			if dup, err := walkJsonValue(dec, keyPath); err != nil || dup != "" {
+				return dup, err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if dup, err := walkJsonValue(dec, joinKey(path, strconv.Itoa(i))); err != nil || dup != "" {
+				return dup, err
+			}
+		}
+	}
+
+	// consume the closing delimiter
+	_, err = dec.Token()
+	return "", err
+}
+
+// findYamlDuplicate decodes the passed YAML data
+// into an order preserving yaml.MapSlice and
+// returns the path of the first duplicate key
+// found. If no duplicate key exists, an empty
+// string is returned.
+func findYamlDuplicate(data []byte) (string, error) {
+	var ms yaml.MapSlice
+	if err := yaml.Unmarshal(data, &ms); err != nil {
+		return "", err
+	}
+	return walkYamlValue(ms, ""), nil
+}
+
+func walkYamlValue(v interface{}, path string) string {
+	switch vt := v.(type) {
+	case yaml.MapSlice:
+		seen := make(map[string]struct{})
+		for _, item := range vt {
+			key := fmt.Sprintf("%v", item.Key)
+			keyPath := joinKey(path, key)
+			if _, ok := seen[key]; ok {
+				return keyPath
+			}
+			seen[key] = struct{}{}
+			if dup := walkYamlValue(item.Value, keyPath); dup != "" {
+				return dup
+			}
+		}
+	case []interface{}:
+		for i, e := range vt {
+			if dup := walkYamlValue(e, joinKey(path, strconv.Itoa(i))); dup != "" {
+				return dup
+			}
+		}
+	}
+	return ""
+}
+
+// joinKey appends key to path using the
+// key delimiter.
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + keyDelimiter + key
+}
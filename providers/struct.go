@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	structTag = "config"
+)
+
+var (
+	// ErrNoStruct is returned by the StructProvider
+	// when the passed value is not a struct or a
+	// pointer to a struct.
+	ErrNoStruct = errors.New("value is not a struct")
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// StructProvider implements the Provider interface
+// for reading config values from a Go struct.
+type StructProvider struct {
+	v        interface{}
+	optional bool
+}
+
+// NewStructProvider produces a new StructProvider
+// instance with the given struct value and optional
+// flag.
+//
+// Fields are mapped to keys by their `config:"name"`
+// tag or by their field name if no tag is set.
+// Fields tagged with `config:"-"` are skipped and
+// nested structs become nested sections.
+func NewStructProvider(v interface{}, optional bool) *StructProvider {
+	return &StructProvider{
+		v:        v,
+		optional: optional,
+	}
+}
+
+func (p *StructProvider) Name() string {
+	return fmt.Sprintf("struct:%T", p.v)
+}
+
+func (p *StructProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *StructProvider) GetMap() (map[string]interface{}, error) {
+	rv := reflect.ValueOf(p.v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		if p.optional && (!rv.IsValid() || rv.Kind() == reflect.Ptr) {
+			return nil, nil
+		}
+		return nil, ErrNoStruct
+	}
+
+	return structToMap(rv), nil
+}
+
+// structToMap maps the fields of the passed
+// struct value to a map respecting the config
+// tags of the fields.
+func structToMap(rv reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{})
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(structTag)
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			inner := structToMap(fv)
+			if field.Anonymous && !ok {
+				for k, v := range inner {
+					m[k] = v
+				}
+				continue
+			}
+			fv = reflect.ValueOf(inner)
+		}
+
+		if !ok || name == "" {
+			name = field.Name
+		}
+		m[name] = fv.Interface()
+	}
+
+	return m
+}
@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TomlProvider implements the Provider interface
+// for reading TOML config files.
+type TomlProvider struct {
+	fileName string
+	optional bool
+}
+
+// NewTomlProvider produces a new TomlProvider instance
+// with the given fileName and optional flag.
+//
+// Tables are mapped to sections and native
+// date-time values are kept as time.Time.
+func NewTomlProvider(fileName string, optional bool) *TomlProvider {
+	return &TomlProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+func (p *TomlProvider) Name() string {
+	return p.fileName
+}
+
+func (p *TomlProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *TomlProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *TomlProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	if err = toml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %s", p.fileName, err.Error())
+	}
+
+	return m, nil
+}
@@ -0,0 +1,158 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	// XmlAttributePrefix is prepended to the
+	// names of XML attributes to build their
+	// config keys.
+	XmlAttributePrefix = "@"
+
+	// XmlTextKey is the config key of the text
+	// content of XML elements which also have
+	// attributes or child elements.
+	XmlTextKey = "#text"
+)
+
+// XmlProvider implements the Provider interface
+// for reading XML config files.
+type XmlProvider struct {
+	fileName   string
+	optional   bool
+	allowEmpty bool
+}
+
+// NewXmlProvider produces a new XmlProvider instance
+// with the given fileName and optional flag.
+//
+// The root element of the document is the root
+// of the config. Child elements are mapped to
+// sections or, if they only contain text, to
+// values. Attributes are mapped to keys prefixed
+// with XmlAttributePrefix and the text of elements
+// with attributes or children is set to the
+// XmlTextKey. Repeated sibling elements become
+// arrays.
+func NewXmlProvider(fileName string, optional bool) *XmlProvider {
+	return &XmlProvider{
+		fileName: fileName,
+		optional: optional,
+	}
+}
+
+// SetAllowEmpty sets whether an existing but
+// empty file, which only contains whitespace, is
+// read as an empty config instead of failing to
+// parse. This also applies if the provider is
+// not optional.
+func (p *XmlProvider) SetAllowEmpty(allowEmpty bool) *XmlProvider {
+	p.allowEmpty = allowEmpty
+	return p
+}
+
+func (p *XmlProvider) Name() string {
+	return p.fileName
+}
+
+func (p *XmlProvider) Optional() bool {
+	return p.optional
+}
+
+func (p *XmlProvider) FileName() string {
+	return p.fileName
+}
+
+func (p *XmlProvider) GetMap() (map[string]interface{}, error) {
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
+		return nil, err
+	}
+	if p.allowEmpty && isBlank(data) {
+		return nil, nil
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", p.fileName, err.Error())
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		v, err := decodeXmlElement(dec, start)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", p.fileName, err.Error())
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{XmlTextKey: v}
+		}
+
+		return m, nil
+	}
+}
+
+// decodeXmlElement decodes the element started
+// by start either to its text content, if it has
+// no attributes and children, or to a map.
+func decodeXmlElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	m := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		m[XmlAttributePrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXmlElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			addXmlChild(m, t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			txt := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return txt, nil
+			}
+			if txt != "" {
+				m[XmlTextKey] = txt
+			}
+			return m, nil
+		}
+	}
+}
+
+// addXmlChild sets v as child name of m. If the
+// child already exists, the values are collected
+// in an array.
+func addXmlChild(m map[string]interface{}, name string, v interface{}) {
+	existing, ok := m[name]
+	if !ok {
+		m[name] = v
+		return
+	}
+
+	if arr, ok := existing.([]interface{}); ok {
+		m[name] = append(arr, v)
+	} else {
+		m[name] = []interface{}{existing, v}
+	}
+}
@@ -1,7 +1,8 @@
 package providers
 
 import (
-	"os"
+	"bytes"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
@@ -9,8 +10,14 @@ import (
 // YamlProvider implements the Provider interface
 // for reading YAML config files.
 type YamlProvider struct {
-	fileName string
-	optional bool
+	fileName       string
+	optional       bool
+	strict         bool
+	yaml12         bool
+	allowEmpty     bool
+	fileDirectives bool
+	keyOrder       bool
+	order          KeyOrder
 }
 
 // NewYamlProvider produces a new YamlProvider instance
@@ -22,23 +29,206 @@ func NewYamlProvider(fileName string, optional bool) *YamlProvider {
 	}
 }
 
+// SetStrict sets whether the file should be
+// checked for duplicate keys before decoding.
+// If a duplicate key is found, GetMap returns
+// a *DuplicateKeyError.
+func (p *YamlProvider) SetStrict(strict bool) *YamlProvider {
+	p.strict = strict
+	return p
+}
+
+// SetYaml12Booleans sets whether only the YAML
+// 1.2 boolean literals true and false are
+// decoded as booleans. If set, YAML 1.1 literals
+// like yes, no, on and off are kept as strings,
+// so that for example `country: no` is read as
+// "no" instead of false.
+func (p *YamlProvider) SetYaml12Booleans(enabled bool) *YamlProvider {
+	p.yaml12 = enabled
+	return p
+}
+
+// SetAllowEmpty sets whether an existing but
+// empty file, which only contains whitespace, is
+// read as an empty config instead of failing to
+// parse. This also applies if the provider is
+// not optional.
+func (p *YamlProvider) SetAllowEmpty(allowEmpty bool) *YamlProvider {
+	p.allowEmpty = allowEmpty
+	return p
+}
+
+// SetFileDirectives sets whether objects which
+// only consist of the key "$file" are replaced
+// by the raw content of the referenced file.
+// Relative paths are resolved against the
+// directory of the including file.
+func (p *YamlProvider) SetFileDirectives(enabled bool) *YamlProvider {
+	p.fileDirectives = enabled
+	return p
+}
+
+// SetKeyOrder sets whether the order of the
+// keys in the file is recorded on GetMap, so
+// that it can be read with KeyOrder.
+func (p *YamlProvider) SetKeyOrder(enabled bool) *YamlProvider {
+	p.keyOrder = enabled
+	return p
+}
+
+// KeyOrder returns the order of the keys read
+// by the last call of GetMap, if recording the
+// key order is enabled.
+func (p *YamlProvider) KeyOrder() KeyOrder {
+	return p.order
+}
+
+func (p *YamlProvider) Name() string {
+	return p.fileName
+}
+
+func (p *YamlProvider) Optional() bool {
+	return p.optional
+}
+
+// FileName returns the name of the read file.
+// If file directives are enabled, an empty
+// string is returned, as the values also depend
+// on the referenced files.
+func (p *YamlProvider) FileName() string {
+	if p.fileDirectives {
+		return ""
+	}
+	return p.fileName
+}
+
 func (p *YamlProvider) GetMap() (map[string]interface{}, error) {
-	_, err := os.Stat(p.fileName)
-	if err != nil {
-		if os.IsNotExist(err) && p.optional {
-			return nil, nil
-		}
+	p.order = nil
+	data, ok, err := readFile(p.fileName, p.optional)
+	if !ok {
 		return nil, err
 	}
+	if p.allowEmpty && isBlank(data) {
+		return nil, nil
+	}
 
-	f, err := os.Open(p.fileName)
+	if p.strict {
+		dup, err := findYamlDuplicate(data)
+		if err != nil {
+			return nil, err
+		}
+		if dup != "" {
+			return nil, &DuplicateKeyError{File: p.fileName, Key: dup}
+		}
+	}
+
+	m, err := p.decode(data)
 	if err != nil {
-		return nil, err
+		return m, err
+	}
+
+	if p.keyOrder {
+		if p.order, err = yamlKeyOrder(data); err != nil {
+			return m, err
+		}
+	}
+
+	if p.fileDirectives {
+		err = resolveFileDirectives(m, filepath.Dir(p.fileName))
+	}
+
+	return m, err
+}
+
+// decode decodes the passed YAML data respecting
+// the set boolean handling.
+func (p *YamlProvider) decode(data []byte) (map[string]interface{}, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	if p.yaml12 {
+		var nm map[string]*yamlNode
+		err := dec.Decode(&nm)
+		m := make(map[string]interface{}, len(nm))
+		for k, n := range nm {
+			m[k] = n.value()
+		}
+		return m, err
 	}
 
 	m := make(map[string]interface{})
-	dec := yaml.NewDecoder(f)
-	err = dec.Decode(&m)
+	err := dec.Decode(&m)
 
 	return m, err
 }
+
+// yamlNode decodes a YAML value like the default
+// decoder but keeps all scalars resolved as YAML
+// 1.1 booleans, except the YAML 1.2 literals
+// true and false, as strings.
+type yamlNode struct {
+	v interface{}
+}
+
+func (n *yamlNode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	switch raw.(type) {
+	case map[interface{}]interface{}:
+		var m map[interface{}]*yamlNode
+		if err := unmarshal(&m); err != nil {
+			return err
+		}
+		res := make(map[interface{}]interface{}, len(m))
+		for k, e := range m {
+			res[k] = e.value()
+		}
+		n.v = res
+	case []interface{}:
+		var s []*yamlNode
+		if err := unmarshal(&s); err != nil {
+			return err
+		}
+		res := make([]interface{}, len(s))
+		for i, e := range s {
+			res[i] = e.value()
+		}
+		n.v = res
+	case bool:
+		var s string
+		if err := unmarshal(&s); err != nil {
+			return err
+		}
+		if isYaml12Bool(s) {
+			n.v = raw
+		} else {
+			n.v = s
+		}
+	default:
+		n.v = raw
+	}
+
+	return nil
+}
+
+// value returns the decoded value of n, which
+// is nil for YAML null values.
+func (n *yamlNode) value() interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.v
+}
+
+// isYaml12Bool returns true if s is a boolean
+// literal in the YAML 1.2 core schema.
+func isYaml12Bool(s string) bool {
+	switch s {
+	case "true", "True", "TRUE", "false", "False", "FALSE":
+		return true
+	}
+	return false
+}
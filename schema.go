@@ -0,0 +1,86 @@
+package configoration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL is the name under which a schema
+// passed to ValidateAgainstSchema is compiled.
+const schemaURL = "config.schema.json"
+
+// compileSchema compiles the passed JSON Schema.
+func compileSchema(schema []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(schemaURL)
+}
+
+// validateSchema validates m as JSON against
+// schema and returns all violations joined as
+// *ValidationError, sorted by key.
+func validateSchema(schema *jsonschema.Schema, m ConfigMap) error {
+	data, err := json.Marshal(plainCopy(m))
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return err
+	}
+
+	err = schema.Validate(v)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	var errs []error
+	collectSchemaErrors(verr, &errs)
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].(*ValidationError).Key < errs[j].(*ValidationError).Key
+	})
+	return errors.Join(errs...)
+}
+
+// collectSchemaErrors appends all leaf causes of
+// verr to errs.
+func collectSchemaErrors(verr *jsonschema.ValidationError, errs *[]error) {
+	if len(verr.Causes) == 0 {
+		rule := verr.KeywordLocation[strings.LastIndexByte(verr.KeywordLocation, '/')+1:]
+		*errs = append(*errs, &ValidationError{
+			Key:  pointerToKey(verr.InstanceLocation),
+			Rule: fmt.Sprintf("%s: %s", rule, verr.Message),
+		})
+		return
+	}
+	for _, cause := range verr.Causes {
+		collectSchemaErrors(cause, errs)
+	}
+}
+
+// pointerToKey converts the passed JSON pointer
+// to a key joined by the Delimiter.
+func pointerToKey(pointer string) string {
+	if pointer == "" {
+		return ""
+	}
+
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, seg := range segments {
+		seg = strings.ReplaceAll(seg, "~1", "/")
+		segments[i] = strings.ReplaceAll(seg, "~0", "~")
+	}
+	return strings.Join(segments, Delimiter)
+}
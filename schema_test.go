@@ -0,0 +1,60 @@
+package configoration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["mode"],
+	"properties": {
+		"mode": { "enum": ["dev", "prod"] },
+		"db": {
+			"type": "object",
+			"properties": {
+				"port": { "type": "integer", "maximum": 65535 }
+			}
+		}
+	}
+}`
+
+func TestValidateAgainstSchema(t *testing.T) {
+	_, err := NewBuilder().
+		AddMap(map[string]interface{}{
+			"mode": "prod",
+			"db":   map[string]interface{}{"port": 5432},
+		}).
+		ValidateAgainstSchema([]byte(testSchema)).
+		Build()
+	assert(t, err, nil)
+
+	_, err = NewBuilder().
+		AddMap(map[string]interface{}{
+			"mode": "staging",
+			"db":   map[string]interface{}{"port": 70000},
+		}).
+		ValidateAgainstSchema([]byte(testSchema)).
+		Build()
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Fatalf("error (%+v) was not like expected (%+v)", err, ErrValidationFailed)
+	}
+
+	var violations []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var verr *ValidationError
+		if !errors.As(e, &verr) {
+			t.Fatalf("violation (%+v) was no ValidationError", e)
+		}
+		violations = append(violations, verr.Key)
+	}
+	assert(t, strings.Join(violations, ","), "db:port,mode")
+
+	_, err = NewBuilder().
+		ValidateAgainstSchema([]byte(`{"type": 1}`)).
+		Build()
+	if err == nil {
+		t.Fatal("invalid schema did not return an error")
+	}
+}
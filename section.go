@@ -1,37 +1,116 @@
 package configoration
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // Section provides functionalities to access
 // sections and values in a Section by a key.
 //
+// Errors returned by the getters are *KeyError
+// instances carrying the requested key. Use
+// errors.Is to check for ErrNil or
+// ErrInvalidType. Scalar getters like
+// GetString return ErrExpectedScalar if the
+// selected value is a slice.
+//
 // A key can be a value or section key itself
 // like "webserver" or it can span over sections
 // like "general:webserver". In this case, the
 // value after the last delimiter is selected
 // section or value.
+//
+// Elements of slices can be selected by their
+// index like "regions:0:code". Numeric sections
+// select an element only if the value is a
+// slice; in maps, they select the map key like
+// any other key. Index selection can be turned
+// off with Builder.DisableArrayIndexing.
 type Section interface {
 	// GetSection returns a section by key.
 	// If the desired section is not existent,
-	// the returned value will be nil.
+	// the returned value will be nil. An empty
+	// key returns the section itself.
 	GetSection(key string) Section
 
+	// GetSectionPath is like GetSection but
+	// takes each argument as one literal
+	// segment, so segments may contain the
+	// Delimiter.
+	GetSectionPath(segments ...string) Section
+
+	// WithPrefix returns a cursor whose getters
+	// resolve all keys prefixed with prefix in
+	// the current section, so that
+	// WithPrefix("a:b").GetString("c") reads
+	// "a:b:c".
+	//
+	// Unlike GetSection, the prefix is resolved
+	// on every call, so the cursor reflects
+	// reloads of the config and can be created
+	// for prefixes which do not exist yet.
+	WithPrefix(prefix string) Section
+
+	// WithDefaults returns a view of the current
+	// section whose getters fall back to the
+	// values in defaults when a key does not
+	// exist, so that GetString returns the
+	// default instead of ErrNil. Sections
+	// returned by GetSection, GetSectionPath and
+	// WithPrefix of the view keep the fallback
+	// and Keys and SectionNames include the keys
+	// of defaults.
+	WithDefaults(defaults ConfigMap) Section
+
 	// GetValue returns an interface value by
 	// key. If the desired value could not be
-	// found, nil and ErrNil is returned.
+	// found, nil and ErrNil is returned. An
+	// empty key never selects a value and
+	// returns ErrNil as well.
+	//
+	// If the value is a map or a slice, a deep
+	// copy is returned, so that modifications
+	// do not affect the config.
 	GetValue(key string) (interface{}, error)
 
+	// GetValueCtx returns an interface value by
+	// key like GetValue. If the value is a
+	// RemoteValue, it is fetched respecting the
+	// deadline and cancellation of ctx and the
+	// error of a failed fetch is returned.
+	// Local values are read regardless of ctx.
+	GetValueCtx(ctx context.Context, key string) (interface{}, error)
+
+	// GetValueCompiled returns an interface value
+	// by a key compiled with CompileKey like
+	// GetValue.
+	GetValueCompiled(ck CompiledKey) (interface{}, error)
+
+	// GetValuePath is like GetValue but takes
+	// each argument as one literal segment, so
+	// segments may contain the Delimiter.
+	GetValuePath(segments ...string) (interface{}, error)
+
 	// GetString is shorthand for GetValue and
 	// returns a string or an ErrNil if the
 	// key was not found.
 	//
 	// If the value selected is not a string,
 	// ErrInvalidType will be returned.
+	//
+	// If Builder.LazyInterpolation is enabled,
+	// ${VAR} tokens in the value are resolved
+	// against the current environment.
 	GetString(key string) (string, error)
 
 	// GetInt is shorthand for GetValue and
@@ -58,6 +137,244 @@ type Section interface {
 	// ErrInvalidType will be returned.
 	GetFloat64(key string) (float64, error)
 
+	// GetStringCompiled is like GetString but
+	// takes a key compiled with CompileKey.
+	GetStringCompiled(ck CompiledKey) (string, error)
+
+	// GetIntCompiled is like GetInt but takes
+	// a key compiled with CompileKey.
+	GetIntCompiled(ck CompiledKey) (int, error)
+
+	// GetBoolCompiled is like GetBool but takes
+	// a key compiled with CompileKey.
+	GetBoolCompiled(ck CompiledKey) (bool, error)
+
+	// GetFloat64Compiled is like GetFloat64 but
+	// takes a key compiled with CompileKey.
+	GetFloat64Compiled(ck CompiledKey) (float64, error)
+
+	// GetValueWithDelimiter is like GetValue but
+	// splits key by delim instead of the
+	// Delimiter, like "a.b" with delim ".".
+	GetValueWithDelimiter(key, delim string) (interface{}, error)
+
+	// GetStringWithDelimiter is like GetString
+	// but splits key by delim.
+	GetStringWithDelimiter(key, delim string) (string, error)
+
+	// GetIntWithDelimiter is like GetInt but
+	// splits key by delim.
+	GetIntWithDelimiter(key, delim string) (int, error)
+
+	// GetBoolWithDelimiter is like GetBool but
+	// splits key by delim.
+	GetBoolWithDelimiter(key, delim string) (bool, error)
+
+	// GetFloat64WithDelimiter is like GetFloat64
+	// but splits key by delim.
+	GetFloat64WithDelimiter(key, delim string) (float64, error)
+
+	// GetFloat32 is shorthand for GetValue and
+	// returns a float32 or an ErrNil if the
+	// key was not found.
+	//
+	// If the value selected is not a float32 or
+	// exceeds its range, an error is returned.
+	GetFloat32(key string) (float32, error)
+
+	// GetInt32 is shorthand for GetValue and
+	// returns an int32 or an ErrNil if the
+	// key was not found.
+	//
+	// If the value selected is not an int32, an
+	// error is returned. Values exceeding its
+	// range are handled according to the
+	// IntOverflowPolicy of the config.
+	GetInt32(key string) (int32, error)
+
+	// GetInt16 is shorthand for GetValue and
+	// returns an int16 or an ErrNil if the
+	// key was not found.
+	//
+	// If the value selected is not an int16, an
+	// error is returned. Values exceeding its
+	// range are handled according to the
+	// IntOverflowPolicy of the config.
+	GetInt16(key string) (int16, error)
+
+	// GetInt8 is shorthand for GetValue and
+	// returns an int8 or an ErrNil if the
+	// key was not found.
+	//
+	// If the value selected is not an int8, an
+	// error is returned. Values exceeding its
+	// range are handled according to the
+	// IntOverflowPolicy of the config.
+	GetInt8(key string) (int8, error)
+
+	// GetPercent is shorthand for GetValue and
+	// returns a fraction in the range [0, 1] or
+	// an ErrNil if the key was not found.
+	//
+	// The value can either be a bare number like
+	// 0.75 or a string with a trailing "%" like
+	// "75%". If the value can not be parsed or is
+	// outside of the range [0, 1], ErrInvalidType
+	// will be returned.
+	GetPercent(key string) (float64, error)
+
+	// GetUUID is shorthand for GetValue and
+	// returns a UUID parsed from the string
+	// value or an ErrNil if the key was not
+	// found.
+	//
+	// If the value can not be parsed as UUID,
+	// ErrInvalidType will be returned.
+	GetUUID(key string) (uuid.UUID, error)
+
+	// GetDurationSeconds is shorthand for GetValue
+	// and returns a duration or an ErrNil if the
+	// key was not found.
+	//
+	// Numbers, also as string like "30", are
+	// interpreted as seconds and other strings
+	// are parsed like "30s" using
+	// time.ParseDuration. If the value can not
+	// be parsed, ErrInvalidType will be returned.
+	GetDurationSeconds(key string) (time.Duration, error)
+
+	// GetByteSize is shorthand for GetValue and
+	// returns a number of bytes or an ErrNil if
+	// the key was not found.
+	//
+	// Numbers are interpreted as bytes and
+	// strings like "10MB" or "512KiB" are parsed
+	// with their decimal or binary unit. If the
+	// value can not be parsed, ErrInvalidType
+	// will be returned.
+	GetByteSize(key string) (int64, error)
+
+	// GetTime is shorthand for GetValue and
+	// returns a time or an ErrNil if the key was
+	// not found.
+	//
+	// Native date-time values of sources like
+	// TOML are returned as is. Strings are
+	// parsed in the RFC 3339 format. If the
+	// value can not be parsed, ErrInvalidType
+	// will be returned.
+	GetTime(key string) (time.Time, error)
+
+	// GetTimeWithLayout is like GetTime but
+	// parses strings with the passed layout as
+	// used by time.Parse.
+	GetTimeWithLayout(key, layout string) (time.Time, error)
+
+	// GetBoolExtended is like GetBool but
+	// additionally accepts the values yes, no,
+	// on, off, y, n, enabled and disabled, case
+	// insensitive.
+	//
+	// If the value selected is none of the
+	// accepted values, ErrInvalidType will be
+	// returned.
+	GetBoolExtended(key string) (bool, error)
+
+	// GetSectionsSlice returns one Section for
+	// each element of the slice value of key or
+	// an ErrNil if the key was not found.
+	//
+	// If the value selected is not a slice or an
+	// element is not a section, ErrInvalidType
+	// will be returned.
+	GetSectionsSlice(key string) ([]Section, error)
+
+	// GetNested parses the string value of key as
+	// JSON object and returns it as Section, so
+	// that values of stringified JSON like
+	// `"{\"a\": 1}"` can be accessed. It returns
+	// an ErrNil if the key was not found.
+	//
+	// If the value selected is no string or no
+	// valid JSON object, ErrInvalidType will be
+	// returned.
+	GetNested(key string) (Section, error)
+
+	// GetStringMap is shorthand for GetValue and
+	// returns the section of key as map or an
+	// ErrNil if the key was not found.
+	//
+	// If the value selected is not a section,
+	// ErrInvalidType will be returned.
+	GetStringMap(key string) (map[string]interface{}, error)
+
+	// GetTypedMap is like GetStringMap but
+	// additionally parses string values which
+	// look like ints, floats or bools into
+	// these types like providers.InferType,
+	// including values of inner sections and
+	// slices.
+	GetTypedMap(key string) (map[string]interface{}, error)
+
+	// GetStringSlice is shorthand for GetValue and
+	// returns a slice of strings or an ErrNil if
+	// the key was not found.
+	//
+	// If the value selected is not a slice,
+	// ErrInvalidType will be returned.
+	//
+	// The elements can be trimmed, filtered and
+	// deduplicated by passing SliceOptions like
+	// WithTrimSpace.
+	GetStringSlice(key string, opts ...SliceOption) ([]string, error)
+
+	// GetStringSliceFlexible is like
+	// GetStringSlice but returns a single scalar
+	// value as slice with one element, so that
+	// both `tags: one` and `tags: [one, two]`
+	// can be read the same way.
+	//
+	// If the value selected is a section,
+	// ErrInvalidType will be returned.
+	GetStringSliceFlexible(key string, opts ...SliceOption) ([]string, error)
+
+	// GetLines returns the string value of key
+	// split into lines. Each line is trimmed and
+	// blank lines as well as comment lines
+	// starting with "#" are dropped, so that
+	// one-entry-per-line lists can be read.
+	//
+	// If the value selected is a section or a
+	// slice, ErrInvalidType will be returned.
+	GetLines(key string) ([]string, error)
+
+	// GetIntSlice is shorthand for GetValue and
+	// returns a slice of ints or an ErrNil if
+	// the key was not found.
+	//
+	// If the value selected is not a slice or an
+	// element is not an int, ErrInvalidType will
+	// be returned.
+	GetIntSlice(key string) ([]int, error)
+
+	// GetFloat64Slice is shorthand for GetValue and
+	// returns a slice of float64s or an ErrNil if
+	// the key was not found.
+	//
+	// If the value selected is not a slice or an
+	// element is not a float64, ErrInvalidType
+	// will be returned.
+	GetFloat64Slice(key string) ([]float64, error)
+
+	// GetBoolSlice is shorthand for GetValue and
+	// returns a slice of bools or an ErrNil if
+	// the key was not found.
+	//
+	// If the value selected is not a slice or an
+	// element is not a bool, ErrInvalidType will
+	// be returned.
+	GetBoolSlice(key string) ([]bool, error)
+
 	// GetValueOrDef returns an interface value
 	// by key. If the desired value could not be
 	// found, def will be returned.
@@ -83,6 +400,104 @@ type Section interface {
 	// found value or the vlaue of def.
 	GetFloat64OrDef(key string, def float64) float64
 
+	// GetStringSliceOrDef is shorthand for
+	// GetStringSlice and returns either the
+	// found slice or def.
+	GetStringSliceOrDef(key string, def []string) []string
+
+	// GetIntSliceOrDef is shorthand for
+	// GetIntSlice and returns either the
+	// found slice or def.
+	GetIntSliceOrDef(key string, def []int) []int
+
+	// GetFloat64SliceOrDef is shorthand for
+	// GetFloat64Slice and returns either the
+	// found slice or def.
+	GetFloat64SliceOrDef(key string, def []float64) []float64
+
+	// GetBoolSliceOrDef is shorthand for
+	// GetBoolSlice and returns either the
+	// found slice or def.
+	GetBoolSliceOrDef(key string, def []bool) []bool
+
+	// GetUUIDOrDef is shorthand for GetUUID and
+	// returns either the found UUID or def.
+	GetUUIDOrDef(key string, def uuid.UUID) uuid.UUID
+
+	// GetStringRequired is like GetString but
+	// returns an error matching
+	// ErrRequiredKeyMissing if the key was not
+	// found.
+	GetStringRequired(key string) (string, error)
+
+	// GetIntRequired is like GetInt but returns
+	// an error matching ErrRequiredKeyMissing if
+	// the key was not found.
+	GetIntRequired(key string) (int, error)
+
+	// GetBoolRequired is like GetBool but returns
+	// an error matching ErrRequiredKeyMissing if
+	// the key was not found.
+	GetBoolRequired(key string) (bool, error)
+
+	// GetFloat64Required is like GetFloat64 but
+	// returns an error matching
+	// ErrRequiredKeyMissing if the key was not
+	// found.
+	GetFloat64Required(key string) (float64, error)
+
+	// UnmarshalKey decodes the value of key into
+	// the value target points to like GetValueAs.
+	// Sections can be decoded into structs and
+	// into maps with string keys, whose values
+	// are converted to the element type.
+	//
+	// If target is not a non-nil pointer,
+	// ErrInvalidTarget is returned.
+	UnmarshalKey(key string, target interface{}) error
+
+	// BindSchema decodes the value of key into
+	// target like UnmarshalKey and validates the
+	// result like ValidateStruct. All violations
+	// are returned joined, so each of them can
+	// be inspected using errors.As.
+	BindSchema(key string, target interface{}) error
+
+	// GetOrSet returns the value of key if it
+	// exists. Otherwise, compute is called and
+	// its result is stored for key, creating
+	// missing sections, and returned. If compute
+	// returns an error, nothing is stored.
+	//
+	// compute is called while the config is
+	// locked, so concurrent calls compute the
+	// value only once. Therefore, compute must
+	// not access the config. Stored values are
+	// discarded when the config is reloaded.
+	GetOrSet(key string, compute func() (interface{}, error)) (interface{}, error)
+
+	// TypeOf returns the ValueType of the value
+	// of key or an ErrNil if the key was not
+	// found.
+	TypeOf(key string) (ValueType, error)
+
+	// CheckTypes compares the ValueType of the
+	// value of each key of expected with the
+	// expected type and returns all mismatches
+	// sorted by key, or nil if all values match.
+	// Integral floats, like JSON numbers, match
+	// TypeInt and ints match TypeFloat.
+	CheckTypes(expected map[string]ValueType) []TypeMismatch
+
+	// Keys returns the sorted keys of all values
+	// and sub-sections of the current section.
+	Keys() []string
+
+	// SectionNames returns the sorted keys of
+	// all sub-sections of the current section,
+	// omitting keys of values.
+	SectionNames() []string
+
 	// IsNil returns true if the current section
 	// instance is nil.
 	IsNil() bool
@@ -91,12 +506,153 @@ type Section interface {
 // section is the default implementation of
 // the Section interface.
 type section struct {
-	mtx sync.Mutex
-	m   ConfigMap
+	// mtx is shared between a section and
+	// all of its child sections.
+	mtx  *sync.Mutex
+	m    ConfigMap
+	opts *sectionOptions
+
+	// children caches the child sections
+	// returned by getSection.
+	children map[string]*section
+
+	// folded maps the lower case keys of m to
+	// the keys of m for case fold fallback
+	// lookups. It is built when the section is
+	// created and dropped when keys are added,
+	// after which it is built on the next miss.
+	folded map[string]string
+
+	// base and prefix are set for cursors
+	// returned by WithPrefix, which resolve
+	// all keys prefixed in base.
+	base   *section
+	prefix string
+
+	// defaults is set for views returned by
+	// WithDefaults and is used for all keys
+	// which do not exist in base.
+	defaults *section
+
+	// root and path are set for the sections of
+	// a built config and are used to look up
+	// missing keys in the inline defaults
+	// section.
+	root *section
+	path []string
+}
+
+// sectionOptions contains the options of a
+// built config which are shared with all of
+// its sections.
+type sectionOptions struct {
+	keyNormalizer   func(string) string
+	typeErrHandler  func(err error)
+	noArrayIndexing bool
+	lazyInterpolate bool
+	overflowPolicy  IntOverflowPolicy
+	readOnly        bool
+	caseFold        bool
+	accessRecorder  AccessRecorder
+	strictTypes     bool
+	inlineDefaults  []string
+}
+
+func (s *section) WithPrefix(prefix string) Section {
+	if s == nil {
+		return nil
+	}
+
+	if s.base != nil && s.defaults == nil {
+		return s.base.WithPrefix(joinPath(s.prefix, prefix))
+	}
+
+	return &section{
+		mtx:    s.mtx,
+		opts:   s.opts,
+		base:   s,
+		prefix: prefix,
+	}
+}
+
+func (s *section) WithDefaults(defaults ConfigMap) Section {
+	if s == nil {
+		return nil
+	}
+
+	m, _ := copyValue(defaults).(ConfigMap)
+	if s.opts != nil && s.opts.keyNormalizer != nil {
+		m = normalizeKeys(m, s.opts.keyNormalizer, "", nil)
+	}
+
+	return &section{
+		mtx:  s.mtx,
+		opts: s.opts,
+		base: s,
+		defaults: &section{
+			mtx:  &sync.Mutex{},
+			m:    m,
+			opts: s.opts,
+		},
+	}
 }
 
 func (s *section) GetSection(key string) Section {
-	for _, nextSelector := range splitSections(key) {
+	if s == nil {
+		return nil
+	}
+
+	if key == "" {
+		return s
+	}
+
+	if s.defaults != nil {
+		if sec := s.base.GetSection(key); sec.IsNil() && s.defaults.GetSection(key).IsNil() {
+			return sec
+		}
+		return s.WithPrefix(key)
+	}
+
+	if s.base != nil {
+		return s.base.GetSection(joinPath(s.prefix, key))
+	}
+
+	for _, nextSelector := range s.splitKey(key) {
+		if s == nil {
+			return nil
+		}
+		s = s.getSection(nextSelector)
+	}
+	return s
+}
+
+func (s *section) GetSectionPath(segments ...string) Section {
+	if s == nil {
+		return nil
+	}
+
+	if s.defaults != nil {
+		sec, _ := s.base.GetSectionPath(segments...).(*section)
+		defaults, _ := s.defaults.GetSectionPath(segments...).(*section)
+		switch {
+		case defaults == nil:
+			return sec
+		case sec == nil:
+			return defaults
+		}
+		return &section{
+			mtx:      s.mtx,
+			opts:     s.opts,
+			base:     sec,
+			defaults: defaults,
+		}
+	}
+
+	if s.base != nil {
+		return s.base.GetSectionPath(s.prefixSegments(segments)...)
+	}
+
+	for _, nextSelector := range s.normalizeSegments(segments) {
 		if s == nil {
 			return nil
 		}
@@ -106,148 +662,1234 @@ func (s *section) GetSection(key string) Section {
 }
 
 func (s *section) GetValue(key string) (interface{}, error) {
+	return s.GetValueCompiled(CompileKey(key))
+}
+
+func (s *section) GetValueCtx(ctx context.Context, key string) (interface{}, error) {
+	ck := CompileKey(key)
+	v, err := s.getValueCtx(ctx, ck)
+	s.recordAccess(ck.key, err)
+	return v, err
+}
+
+func (s *section) GetValueCompiled(ck CompiledKey) (interface{}, error) {
+	v, err := s.getValueCtx(context.Background(), ck)
+	s.recordAccess(ck.key, err)
+	return v, err
+}
+
+// recordAccess passes the outcome of reading
+// key to the access recorder, if set. The key
+// is recorded by its full path from the root.
+func (s *section) recordAccess(key string, err error) {
+	if s != nil && s.opts != nil && s.opts.accessRecorder != nil {
+		s.opts.accessRecorder.RecordAccess(s.fullKey(key), err)
+	}
+}
+
+// fullKey returns key joined to the path of s
+// from the root.
+func (s *section) fullKey(key string) string {
+	if s.base != nil {
+		return s.base.fullKey(joinPath(s.prefix, key))
+	}
+	if len(s.path) == 0 {
+		return key
+	}
+	return joinPath(strings.Join(s.path, Delimiter), key)
+}
+
+// getValueCtx returns the value of ck and
+// fetches it using ctx if it is a RemoteValue.
+func (s *section) getValueCtx(ctx context.Context, ck CompiledKey) (interface{}, error) {
 	if s == nil {
-		return nil, ErrNil
+		return nil, newKeyError(ck.key, ErrNil)
+	}
+
+	if ck.key == "" {
+		return nil, newTraversalError("", "", KindKeyMissing)
 	}
 
-	selectors := splitSections(key)
+	if s.base != nil {
+		v, err := s.base.getValueCtx(ctx, CompiledKey{
+			key:      joinPath(s.prefix, ck.key),
+			segments: s.prefixSegments(ck.segments),
+		})
+		if s.defaults != nil && errors.Is(err, ErrNil) {
+			if dv, derr := s.defaults.getValueCtx(ctx, ck); derr == nil {
+				return dv, nil
+			}
+		}
+		return v, err
+	}
+
+	selectors := s.normalizeSegments(ck.segments)
+	v, err := s.resolveValue(ctx, ck, selectors)
+	if errors.Is(err, ErrNil) {
+		if dv, ok := s.inlineDefault(ctx, selectors); ok {
+			return dv, nil
+		}
+	}
+	return v, err
+}
+
+// resolveValue returns the value of ck, whose
+// normalized segments are selectors, in s.
+func (s *section) resolveValue(ctx context.Context, ck CompiledKey,
+	selectors []string) (interface{}, error) {
+
 	lenSelectors := len(selectors)
 	if lenSelectors > 1 {
 		for i := 0; i < lenSelectors-1; i++ {
-			s = s.getSection(selectors[i])
-			if s == nil {
-				return nil, ErrNil
+			next := s.getSection(selectors[i])
+			if next == nil {
+				kind := KindSectionMissing
+				if s.has(selectors[i]) {
+					kind = KindNotSection
+				}
+				return nil, newTraversalError(ck.key, strings.Join(selectors[:i+1], Delimiter), kind)
 			}
+			s = next
 		}
 	}
 
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+	s.lock()
+	v, ok := s.lookup(selectors[lenSelectors-1])
+	rv, remote := v.(RemoteValue)
+	if !remote {
+		v = copyValue(v)
+	}
+	s.unlock()
 
-	v, ok := s.m[selectors[lenSelectors-1]]
 	if !ok {
-		return nil, ErrNil
+		return nil, newTraversalError(ck.key,
+			strings.Join(selectors[:lenSelectors-1], Delimiter), KindKeyMissing)
+	}
+
+	if remote {
+		fv, err := rv.Fetch(ctx)
+		if err != nil {
+			return nil, newKeyError(ck.key, err)
+		}
+		return copyValue(fv), nil
 	}
 
 	return v, nil
 }
 
-func (s *section) GetString(key string) (string, error) {
-	v, err := s.GetValue(key)
-	if err != nil {
-		return "", err
+func (s *section) GetValuePath(segments ...string) (interface{}, error) {
+	ck := CompiledKey{
+		key:      strings.Join(segments, Delimiter),
+		segments: segments,
+	}
+	if len(segments) == 0 {
+		ck.segments = []string{""}
 	}
+	return s.GetValueCompiled(ck)
+}
 
-	vt, ok := v.(string)
-	if !ok {
-		vt = valToString(v)
+func (s *section) GetOrSet(key string, compute func() (interface{}, error)) (interface{}, error) {
+	if s == nil {
+		return nil, newKeyError(key, ErrNil)
 	}
 
-	return vt, nil
-}
+	if s.base != nil {
+		return s.base.GetOrSet(joinPath(s.prefix, key), compute)
+	}
 
-func (s *section) GetInt(key string) (int, error) {
-	v, err := s.GetValue(key)
-	if err != nil {
-		return 0, err
+	if s.readOnly() {
+		return nil, newKeyError(key, ErrReadOnly)
 	}
 
-	vt, ok := v.(int)
-	if !ok {
-		vt, err = strconv.Atoi(valToString(v))
+	selectors := s.splitKey(key)
+	last := len(selectors) - 1
+
+	s.lock()
+	defer s.unlock()
+
+	// missing sections are only created after
+	// compute succeeded, so i is the index of the
+	// first missing section
+	m := s.m
+	i := 0
+	for ; i < last; i++ {
+		sel := s.existingKey(m, selectors[i])
+		v, exists := m[sel]
+		if !exists {
+			break
+		}
+		if isLazySection(v) {
+			v = copyValue(v)
+		}
+		next, ok := toConfigMap(v)
+		if !ok {
+			return nil, newTraversalError(key, strings.Join(selectors[:i+1], Delimiter), KindNotSection)
+		}
+		m[sel] = next
+		m = next
 	}
 
-	return vt, err
-}
+	if i == last {
+		if v, ok := m[s.existingKey(m, selectors[last])]; ok {
+			return copyValue(v), nil
+		}
+	}
 
-func (s *section) GetBool(key string) (bool, error) {
-	v, err := s.GetValue(key)
+	v, err := compute()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	vt, ok := v.(bool)
-	if !ok {
-		vt, err = strconv.ParseBool(valToString(v))
+	if s.root != nil {
+		s.root.dropFolded()
+	} else {
+		s.dropFolded()
+	}
+	for ; i < last; i++ {
+		next := make(ConfigMap)
+		m[selectors[i]] = next
+		m = next
 	}
+	m[selectors[last]] = v
 
-	return vt, err
+	return copyValue(v), nil
 }
 
-func (s *section) GetFloat64(key string) (float64, error) {
-	v, err := s.GetValue(key)
+func (s *section) GetString(key string) (string, error) {
+	return s.GetStringCompiled(CompileKey(key))
+}
+
+func (s *section) GetStringCompiled(ck CompiledKey) (string, error) {
+	v, err := s.getScalarCompiled(ck)
 	if err != nil {
-		return 0, err
+		return "", err
+	}
+	if err = s.checkStrictType(ck.key, v, TypeString); err != nil {
+		return "", err
 	}
 
-	vt, ok := v.(float64)
-	if !ok {
-		vt, err = strconv.ParseFloat(valToString(v), 64)
+	if s.opts != nil && s.opts.lazyInterpolate {
+		return expandEnvTokens(toString(v)), nil
 	}
+	return toString(v), nil
+}
 
-	return vt, err
+func (s *section) GetInt(key string) (int, error) {
+	return s.GetIntCompiled(CompileKey(key))
 }
 
-func (s *section) GetValueOrDef(key string, def interface{}) interface{} {
-	v, err := s.GetValue(key)
+func (s *section) GetIntCompiled(ck CompiledKey) (int, error) {
+	v, err := s.getScalarCompiled(ck)
 	if err != nil {
-		v = def
+		return 0, err
+	}
+	if err = s.checkStrictType(ck.key, v, TypeInt, TypeFloat); err != nil {
+		return 0, err
 	}
-	return v
-}
 
-func (s *section) GetStringOrDef(key string, def string) string {
-	v, err := s.GetString(key)
+	vt, err := toInt(v)
 	if err != nil {
-		v = def
+		return 0, newConversionError(ck.key, err)
 	}
-	return v
+
+	return vt, nil
 }
 
-func (s *section) GetIntOrDef(key string, def int) int {
-	v, err := s.GetInt(key)
-	if err != nil {
-		v = def
-	}
-	return v
+func (s *section) GetBool(key string) (bool, error) {
+	return s.GetBoolCompiled(CompileKey(key))
 }
 
-func (s *section) GetBoolOrDef(key string, def bool) interface{} {
-	v, err := s.GetBool(key)
+func (s *section) GetBoolCompiled(ck CompiledKey) (bool, error) {
+	v, err := s.getScalarCompiled(ck)
 	if err != nil {
-		v = def
+		return false, err
+	}
+	if err = s.checkStrictType(ck.key, v, TypeBool); err != nil {
+		return false, err
 	}
-	return v
-}
 
-func (s *section) GetFloat64OrDef(key string, def float64) float64 {
-	v, err := s.GetFloat64(key)
+	vt, err := toBool(v)
 	if err != nil {
-		v = def
+		return false, newConversionError(ck.key, err)
 	}
-	return v
+
+	return vt, nil
 }
 
-func (s *section) IsNil() bool {
-	return s == nil
+func (s *section) GetFloat64(key string) (float64, error) {
+	return s.GetFloat64Compiled(CompileKey(key))
 }
 
-// getSection returns the desired section
-// or nil, if not found.
-func (s *section) getSection(sec string) *section {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+func (s *section) GetFloat64Compiled(ck CompiledKey) (float64, error) {
+	v, err := s.getScalarCompiled(ck)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.checkStrictType(ck.key, v, TypeFloat, TypeInt); err != nil {
+		return 0, err
+	}
+
+	vt, err := toFloat64(v)
+	if err != nil {
+		return 0, newConversionError(ck.key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetValueWithDelimiter(key, delim string) (interface{}, error) {
+	return s.GetValueCompiled(CompileKeyWithDelimiter(key, delim))
+}
+
+func (s *section) GetStringWithDelimiter(key, delim string) (string, error) {
+	return s.GetStringCompiled(CompileKeyWithDelimiter(key, delim))
+}
 
-	v := s.m[sec]
-	vc, ok := v.(ConfigMap)
+func (s *section) GetIntWithDelimiter(key, delim string) (int, error) {
+	return s.GetIntCompiled(CompileKeyWithDelimiter(key, delim))
+}
+
+func (s *section) GetBoolWithDelimiter(key, delim string) (bool, error) {
+	return s.GetBoolCompiled(CompileKeyWithDelimiter(key, delim))
+}
+
+func (s *section) GetFloat64WithDelimiter(key, delim string) (float64, error) {
+	return s.GetFloat64Compiled(CompileKeyWithDelimiter(key, delim))
+}
+
+func (s *section) GetFloat32(key string) (float32, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.checkStrictType(key, v, TypeFloat, TypeInt); err != nil {
+		return 0, err
+	}
+
+	vt, ok := v.(float32)
 	if !ok {
-		return nil
+		vf, err := strconv.ParseFloat(toString(v), 32)
+		if err != nil {
+			return 0, newConversionError(key, err)
+		}
+		vt = float32(vf)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetInt32(key string) (int32, error) {
+	v, err := s.getSizedInt(key, 32)
+	return int32(v), err
+}
+
+func (s *section) GetInt16(key string) (int16, error) {
+	v, err := s.getSizedInt(key, 16)
+	return int16(v), err
+}
+
+func (s *section) GetInt8(key string) (int8, error) {
+	v, err := s.getSizedInt(key, 8)
+	return int8(v), err
+}
+
+func (s *section) GetPercent(key string) (float64, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return 0, err
+	}
+
+	vt, ok := v.(float64)
+	if !ok {
+		vs := strings.TrimSpace(toString(v))
+		isPercent := strings.HasSuffix(vs, "%")
+		if isPercent {
+			vs = strings.TrimSpace(vs[:len(vs)-1])
+		}
+		if vt, err = strconv.ParseFloat(vs, 64); err != nil {
+			return 0, newConversionError(key, err)
+		}
+		if isPercent {
+			vt /= 100
+		}
+	}
+
+	if vt < 0 || vt > 1 {
+		return 0, newConversionError(key, fmt.Errorf("percentage %v is out of range [0, 1]", vt))
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetUUID(key string) (uuid.UUID, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
+	vt, err := uuid.Parse(toString(v))
+	if err != nil {
+		return uuid.Nil, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetDurationSeconds(key string) (time.Duration, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return 0, err
+	}
+
+	vt, err := toDurationSeconds(v)
+	if err != nil {
+		return 0, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetByteSize(key string) (int64, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return 0, err
+	}
+
+	vt, err := toByteSize(v)
+	if err != nil {
+		return 0, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetTime(key string) (time.Time, error) {
+	return s.GetTimeWithLayout(key, time.RFC3339Nano)
+}
+
+func (s *section) GetTimeWithLayout(key, layout string) (time.Time, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if vt, ok := v.(time.Time); ok {
+		return vt, nil
+	}
+
+	vt, err := time.Parse(layout, strings.TrimSpace(toString(v)))
+	if err != nil {
+		return time.Time{}, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetBoolExtended(key string) (bool, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return false, err
+	}
+
+	vt, err := toBoolExtended(v)
+	if err != nil {
+		return false, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+func (s *section) GetNested(key string) (Section, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, ok := v.(string)
+	if !ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+
+	var raw map[string]interface{}
+	if err = json.Unmarshal([]byte(vs), &raw); err != nil {
+		return nil, newConversionError(key, err)
+	}
+	if raw == nil {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+	if s.opts != nil && s.opts.keyNormalizer != nil {
+		raw = normalizeKeys(raw, s.opts.keyNormalizer, "", nil)
+	}
+
+	m := make(ConfigMap)
+	m.merge(raw)
+
 	return &section{
-		mtx: sync.Mutex{},
-		m:   vc,
+		mtx:  &sync.Mutex{},
+		m:    m,
+		opts: s.opts,
+	}, nil
+}
+
+func (s *section) GetSectionsSlice(key string) ([]Section, error) {
+	vs, err := s.getSlice(key)
+	if err != nil {
+		return nil, err
+	}
+
+	root, path := s.rootPath(splitSections(key))
+	res := make([]Section, len(vs))
+	for i, v := range vs {
+		vm, ok := toConfigMap(v)
+		if !ok {
+			return nil, newKeyError(joinPath(key, strconv.Itoa(i)), ErrInvalidType)
+		}
+		elem := &section{
+			mtx:  s.mtx,
+			m:    vm,
+			opts: s.opts,
+			root: root,
+			path: childPath(path, strconv.Itoa(i)),
+		}
+		if s.opts != nil && s.opts.caseFold {
+			elem.folded = foldKeys(vm)
+		}
+		res[i] = elem
+	}
+
+	return res, nil
+}
+
+func (s *section) GetStringMap(key string) (map[string]interface{}, error) {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := toConfigMap(v)
+	if !ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+
+	return map[string]interface{}(m), nil
+}
+
+func (s *section) GetTypedMap(key string) (map[string]interface{}, error) {
+	m, err := s.GetStringMap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range m {
+		m[k] = inferValue(v)
+	}
+
+	return m, nil
+}
+
+func (s *section) GetStringSlice(key string, opts ...SliceOption) ([]string, error) {
+	vs, err := s.getSlice(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(vs))
+	for i, v := range vs {
+		res[i] = toString(v)
+	}
+
+	return applySliceOptions(res, opts), nil
+}
+
+func (s *section) GetStringSliceFlexible(key string, opts ...SliceOption) ([]string, error) {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := toConfigMap(v); ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+
+	vs, ok := toSlice(v)
+	if !ok {
+		return applySliceOptions([]string{toString(v)}, opts), nil
+	}
+
+	res := make([]string, len(vs))
+	for i, v := range vs {
+		res[i] = toString(v)
+	}
+
+	return applySliceOptions(res, opts), nil
+}
+
+func (s *section) GetLines(key string) ([]string, error) {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := toConfigMap(v); ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+	if _, ok := toSlice(v); ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+
+	var res []string
+	for _, line := range strings.Split(toString(v), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+
+	return res, nil
+}
+
+func (s *section) GetIntSlice(key string) ([]int, error) {
+	vs, err := s.getSlice(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]int, len(vs))
+	for i, v := range vs {
+		if res[i], err = toInt(v); err != nil {
+			return nil, newConversionError(joinPath(key, strconv.Itoa(i)), err)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *section) GetFloat64Slice(key string) ([]float64, error) {
+	vs, err := s.getSlice(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]float64, len(vs))
+	for i, v := range vs {
+		if res[i], err = toFloat64(v); err != nil {
+			return nil, newConversionError(joinPath(key, strconv.Itoa(i)), err)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *section) GetBoolSlice(key string) ([]bool, error) {
+	vs, err := s.getSlice(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]bool, len(vs))
+	for i, v := range vs {
+		if res[i], err = toBool(v); err != nil {
+			return nil, newConversionError(joinPath(key, strconv.Itoa(i)), err)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *section) GetValueOrDef(key string, def interface{}) interface{} {
+	v, err := s.GetValue(key)
+	if err != nil {
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetStringOrDef(key string, def string) string {
+	v, err := s.GetString(key)
+	if err != nil {
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetIntOrDef(key string, def int) int {
+	v, err := s.GetInt(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetBoolOrDef(key string, def bool) interface{} {
+	v, err := s.GetBool(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetFloat64OrDef(key string, def float64) float64 {
+	v, err := s.GetFloat64(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetStringSliceOrDef(key string, def []string) []string {
+	v, err := s.GetStringSlice(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetIntSliceOrDef(key string, def []int) []int {
+	v, err := s.GetIntSlice(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetFloat64SliceOrDef(key string, def []float64) []float64 {
+	v, err := s.GetFloat64Slice(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetBoolSliceOrDef(key string, def []bool) []bool {
+	v, err := s.GetBoolSlice(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetUUIDOrDef(key string, def uuid.UUID) uuid.UUID {
+	v, err := s.GetUUID(key)
+	if err != nil {
+		s.reportTypeErr(err)
+		v = def
+	}
+	return v
+}
+
+func (s *section) GetStringRequired(key string) (string, error) {
+	v, err := s.GetString(key)
+	return v, requiredErr(key, err)
+}
+
+func (s *section) GetIntRequired(key string) (int, error) {
+	v, err := s.GetInt(key)
+	return v, requiredErr(key, err)
+}
+
+func (s *section) GetBoolRequired(key string) (bool, error) {
+	v, err := s.GetBool(key)
+	return v, requiredErr(key, err)
+}
+
+func (s *section) GetFloat64Required(key string) (float64, error) {
+	v, err := s.GetFloat64(key)
+	return v, requiredErr(key, err)
+}
+
+func (s *section) Keys() []string {
+	return s.keys(func(interface{}) bool { return true })
+}
+
+func (s *section) SectionNames() []string {
+	return s.keys(func(v interface{}) bool {
+		if isSectionValue(v) {
+			return true
+		}
+		_, ok := toConfigMap(v)
+		return ok
+	})
+}
+
+// keys returns the sorted keys of s whose values
+// match filter.
+func (s *section) keys(filter func(v interface{}) bool) []string {
+	if s == nil {
+		return nil
+	}
+
+	if s.base != nil {
+		sec := s.base
+		if s.prefix != "" {
+			sec, _ = s.base.GetSectionPath(splitSections(s.prefix)...).(*section)
+		}
+		if s.defaults == nil {
+			return sec.keys(filter)
+		}
+		return mergeKeys(sec.keys(filter), s.defaults.keys(filter))
+	}
+
+	s.lock()
+	defer s.unlock()
+
+	keys := make([]string, 0, len(s.m))
+	for k, v := range s.m {
+		if filter(v) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// rootPath returns the root of s and the full
+// normalized path of segments from the root.
+func (s *section) rootPath(segments []string) (*section, []string) {
+	if s.base != nil {
+		return s.base.rootPath(s.prefixSegments(segments))
+	}
+	path := make([]string, 0, len(s.path)+len(segments))
+	return s.root, append(append(path, s.path...), s.normalizeSegments(segments)...)
+}
+
+// mergeKeys returns the sorted union of the
+// sorted keys a and b.
+func mergeKeys(a, b []string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			keys, a = append(keys, a[0]), a[1:]
+		case a[0] > b[0]:
+			keys, b = append(keys, b[0]), b[1:]
+		default:
+			keys, a, b = append(keys, a[0]), a[1:], b[1:]
+		}
+	}
+	return append(append(keys, a...), b...)
+}
+
+func (s *section) IsNil() bool {
+	return s == nil
+}
+
+// reportTypeErr passes err to the invalid type
+// handler, if set and if err is caused by a
+// value of an invalid type.
+func (s *section) reportTypeErr(err error) {
+	if s == nil || s.opts == nil || s.opts.typeErrHandler == nil {
+		return
+	}
+	if errors.Is(err, ErrInvalidType) {
+		s.opts.typeErrHandler(err)
+	}
+}
+
+// inlineDefault returns the value of the key
+// with the passed segments, relative to s, from
+// the inline defaults section, if set. Keys
+// within the defaults section itself have no
+// defaults.
+func (s *section) inlineDefault(ctx context.Context, selectors []string) (interface{}, bool) {
+	if s.root == nil || s.opts == nil || len(s.opts.inlineDefaults) == 0 {
+		return nil, false
+	}
+
+	defaults := s.opts.inlineDefaults
+	full := append(append([]string{}, s.path...), selectors...)
+	if len(full) >= len(defaults) && strings.Join(full[:len(defaults)], Delimiter) ==
+		strings.Join(defaults, Delimiter) {
+		return nil, false
+	}
+
+	segments := append(append([]string{}, defaults...), full...)
+	v, err := s.root.resolveValue(ctx, CompiledKey{
+		key:      strings.Join(segments, Delimiter),
+		segments: segments,
+	}, segments)
+	return v, err == nil
+}
+
+// checkStrictType returns an error matching
+// ErrInvalidType for key if strict types are
+// enabled and v is of none of the passed types.
+func (s *section) checkStrictType(key string, v interface{}, types ...ValueType) error {
+	if s.opts == nil || !s.opts.strictTypes {
+		return nil
+	}
+
+	vt := typeOf(v)
+	for _, t := range types {
+		if vt == t {
+			return nil
+		}
+	}
+	return newKeyError(key, ErrInvalidType)
+}
+
+// getSection returns the desired section
+// or nil, if not found.
+//
+// Found sections are cached, so subsequent
+// calls return the same instance.
+func (s *section) getSection(sec string) *section {
+	s.lock()
+	defer s.unlock()
+
+	if child, ok := s.children[sec]; ok {
+		return child
+	}
+
+	v, _ := s.lookup(sec)
+	if _, exact := s.m[sec]; exact && isLazySection(v) && !s.readOnly() {
+		// the decoded map is copied, so that keys
+		// added to the section are not stored in
+		// the cache of the lazy value
+		v = copyValue(v)
+		s.m[sec] = v
+	}
+	v = resolveLazy(v)
+	vc, ok := toConfigMap(v)
+	if !ok && (s.opts == nil || !s.opts.noArrayIndexing) {
+		vc, ok = toSectionMap(v)
+	}
+	if !ok {
+		return nil
+	}
+
+	child := &section{
+		mtx:  s.mtx,
+		m:    vc,
+		opts: s.opts,
+		root: s.root,
+		path: childPath(s.path, sec),
+	}
+	if s.opts != nil && s.opts.caseFold {
+		child.folded = foldKeys(vc)
+	}
+
+	if s.readOnly() {
+		return child
+	}
+
+	if s.children == nil {
+		s.children = make(map[string]*section)
+	}
+	s.children[sec] = child
+
+	return child
+}
+
+// resolveChildren recursively caches the child
+// sections of all sub-sections of s, so that
+// read-only configs do not have to modify the
+// cache on reads. Lazy values and slices are
+// not resolved.
+func (s *section) resolveChildren() {
+	for k, v := range s.m {
+		vc, ok := toConfigMap(v)
+		if !ok {
+			continue
+		}
+		child := &section{
+			mtx:  s.mtx,
+			m:    vc,
+			opts: s.opts,
+			root: s.root,
+			path: childPath(s.path, k),
+		}
+		child.resolveChildren()
+		if child.opts != nil && child.opts.caseFold {
+			child.folded = foldKeys(child.m)
+		}
+		if s.children == nil {
+			s.children = make(map[string]*section)
+		}
+		s.children[k] = child
+	}
+}
+
+// childPath returns a copy of path with sec
+// appended.
+func childPath(path []string, sec string) []string {
+	return append(append(make([]string, 0, len(path)+1), path...), sec)
+}
+
+// readOnly returns true if s belongs to a
+// config built with Builder.ReadOnly.
+func (s *section) readOnly() bool {
+	return s.opts != nil && s.opts.readOnly
+}
+
+// lock locks s.mtx unless s is read-only.
+func (s *section) lock() {
+	if !s.readOnly() {
+		s.mtx.Lock()
+	}
+}
+
+// unlock unlocks s.mtx unless s is read-only.
+func (s *section) unlock() {
+	if !s.readOnly() {
+		s.mtx.Unlock()
+	}
+}
+
+// invalidateCaches drops all cached child
+// sections of s, so that subsequent lookups
+// resolve them from the current map again, and
+// rebuilds the case fold index of s.
+// s.mtx must be held by the caller.
+func (s *section) invalidateCaches() {
+	s.children = nil
+	s.folded = nil
+	if s.opts != nil && s.opts.caseFold {
+		s.folded = foldKeys(s.m)
+	}
+}
+
+// lookup returns the value of key in s. If key
+// does not exist and the case fold fallback is
+// enabled, the value of the key matching key
+// case insensitively is returned. s.mtx must be
+// held by the caller.
+func (s *section) lookup(key string) (interface{}, bool) {
+	v, ok := s.m[key]
+	if ok || s.opts == nil || !s.opts.caseFold {
+		return v, ok
+	}
+
+	folded := s.folded
+	if folded == nil {
+		folded = foldKeys(s.m)
+		if !s.readOnly() {
+			s.folded = folded
+		}
+	}
+
+	if k, ok := folded[strings.ToLower(key)]; ok {
+		return s.m[k], true
+	}
+	return nil, false
+}
+
+// existingKey returns the key of m matching key
+// if case folding is enabled. Like on lookup, the
+// exact key is preferred over the lexically
+// smallest key folding to the same key. If no
+// key matches, key is returned.
+func (s *section) existingKey(m ConfigMap, key string) string {
+	if _, ok := m[key]; ok || s.opts == nil || !s.opts.caseFold {
+		return key
+	}
+
+	lk := strings.ToLower(key)
+	res, found := key, false
+	for k := range m {
+		if strings.ToLower(k) == lk && (!found || k < res) {
+			res, found = k, true
+		}
+	}
+	return res
+}
+
+// dropFolded drops the case fold indexes of s
+// and all of its cached child sections after a
+// key was added, so that they are built again
+// on the next miss. s.mtx must be held by the
+// caller.
+func (s *section) dropFolded() {
+	s.folded = nil
+	for _, child := range s.children {
+		child.dropFolded()
+	}
+}
+
+// foldKeys returns a map of the lower case keys
+// of m to the keys of m. If multiple keys fold
+// to the same key, the lexically smallest one
+// is selected.
+func foldKeys(m ConfigMap) map[string]string {
+	folded := make(map[string]string, len(m))
+	for k := range m {
+		lk := strings.ToLower(k)
+		if prev, ok := folded[lk]; !ok || k < prev {
+			folded[lk] = k
+		}
+	}
+	return folded
+}
+
+// cachedSections returns the number of cached
+// child sections of s, including the cached
+// children of these.
+func (s *section) cachedSections() int {
+	s.lock()
+	children := make([]*section, 0, len(s.children))
+	for _, child := range s.children {
+		children = append(children, child)
+	}
+	s.unlock()
+
+	n := len(children)
+	for _, child := range children {
+		n += child.cachedSections()
+	}
+	return n
+}
+
+// has returns true if key exists in s.
+func (s *section) has(key string) bool {
+	s.lock()
+	defer s.unlock()
+
+	_, ok := s.lookup(key)
+	return ok
+}
+
+// toSectionMap returns v as ConfigMap if v can
+// be accessed as section. Slices are mapped by
+// their indices, so that elements can be
+// selected by keys like "list:0".
+func toSectionMap(v interface{}) (ConfigMap, bool) {
+	if m, ok := toConfigMap(v); ok {
+		return m, true
+	}
+
+	vs, ok := toSlice(v)
+	if !ok {
+		return nil, false
+	}
+
+	m := make(ConfigMap, len(vs))
+	for i, e := range vs {
+		m[strconv.Itoa(i)] = e
+	}
+	return m, true
+}
+
+// toConfigMap returns v as ConfigMap if v is
+// a map. Otherwise, ok is false.
+func toConfigMap(v interface{}) (ConfigMap, bool) {
+	switch vt := v.(type) {
+	case ConfigMap:
+		return vt, true
+	case map[string]interface{}:
+		return ConfigMap(vt), true
+	case map[interface{}]interface{}:
+		m := make(ConfigMap, len(vt))
+		for k, e := range vt {
+			m[fmt.Sprintf("%v", k)] = e
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// getSlice returns the elements of the slice
+// value of key. If the value is not a slice,
+// ErrInvalidType is returned.
+func (s *section) getSlice(key string) ([]interface{}, error) {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, ok := toSlice(v)
+	if !ok {
+		return nil, newKeyError(key, ErrInvalidType)
+	}
+
+	return vs, nil
+}
+
+// getScalar returns the value of key like
+// GetValue but fails with ErrExpectedScalar if
+// the value is a slice.
+func (s *section) getScalar(key string) (interface{}, error) {
+	return s.getScalarCompiled(CompileKey(key))
+}
+
+// getScalarCompiled is like getScalar for a
+// compiled key.
+func (s *section) getScalarCompiled(ck CompiledKey) (interface{}, error) {
+	v, err := s.GetValueCompiled(ck)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := toSlice(v); ok {
+		return nil, newKeyError(ck.key, ErrExpectedScalar)
+	}
+
+	return v, nil
+}
+
+// getSizedInt returns the value of key parsed
+// as an integer which must fit into bitSize.
+// Values exceeding the range are handled by
+// the IntOverflowPolicy of the config.
+func (s *section) getSizedInt(key string, bitSize int) (int64, error) {
+	v, err := s.getScalar(key)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.checkStrictType(key, v, TypeInt, TypeFloat); err != nil {
+		return 0, err
+	}
+
+	var policy IntOverflowPolicy
+	if s.opts != nil {
+		policy = s.opts.overflowPolicy
+	}
+
+	vt, err := parseSizedInt(toString(v), bitSize, policy)
+	if err != nil {
+		return 0, newConversionError(key, err)
+	}
+
+	return vt, nil
+}
+
+// splitKey splits the passed key into its
+// sections and applies the key normalizer,
+// if set, to each section.
+func (s *section) splitKey(key string) []string {
+	return s.normalizeSegments(splitSections(key))
+}
+
+// prefixSegments returns segments prefixed with
+// the segments of the prefix of the cursor s.
+func (s *section) prefixSegments(segments []string) []string {
+	if s.prefix == "" {
+		return segments
+	}
+	return append(splitSections(s.prefix), segments...)
+}
+
+// normalizeSegments returns a copy of segments
+// with the key normalizer applied to each
+// segment. If no key normalizer is set,
+// segments is returned as is.
+func (s *section) normalizeSegments(segments []string) []string {
+	if s.opts == nil || s.opts.keyNormalizer == nil {
+		return segments
+	}
+
+	normalized := make([]string, len(segments))
+	for i, seg := range segments {
+		normalized[i] = s.opts.keyNormalizer(seg)
 	}
+	return normalized
 }
 
 // splitSections splits the passed key by
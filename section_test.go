@@ -1,8 +1,15 @@
 package configoration
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestGetSection(t *testing.T) {
@@ -32,6 +39,85 @@ func TestGetValue(t *testing.T) {
 	}
 }
 
+func TestGetScalarFromSlice(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"hosts": []interface{}{"a", "b"},
+		"ports": []int{80, 443},
+	})
+
+	{
+		_, err := s.GetString("hosts")
+		if !errors.Is(err, ErrExpectedScalar) || !errors.Is(err, ErrInvalidType) {
+			t.Errorf("slice value did not return ErrExpectedScalar: %v", err)
+		}
+	}
+	{
+		_, err := s.GetInt("ports")
+		if !errors.Is(err, ErrExpectedScalar) {
+			t.Errorf("slice value did not return ErrExpectedScalar: %v", err)
+		}
+	}
+	{
+		_, err := s.GetInt32("ports")
+		if !errors.Is(err, ErrExpectedScalar) {
+			t.Errorf("slice value did not return ErrExpectedScalar: %v", err)
+		}
+	}
+	{
+		rec, err := s.GetString("hosts:1")
+		assertVal(t, rec, err, "b")
+	}
+}
+
+type mockRemoteValue struct {
+	v     interface{}
+	calls int32
+}
+
+func (r *mockRemoteValue) Fetch(ctx context.Context) (interface{}, error) {
+	atomic.AddInt32(&r.calls, 1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Millisecond):
+		return r.v, nil
+	}
+}
+
+func TestGetValueCtx(t *testing.T) {
+	remote := &mockRemoteValue{v: "secret"}
+	s := makeSection(ConfigMap{
+		"local": "value",
+		"db": ConfigMap{
+			"password": remote,
+		},
+	})
+
+	{
+		rec, err := s.GetValueCtx(context.Background(), "db:password")
+		assertVal(t, rec, err, "secret")
+	}
+	{
+		rec, err := s.GetString("db:password")
+		assertVal(t, rec, err, "secret")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	{
+		_, err := s.GetValueCtx(ctx, "db:password")
+		var kErr *KeyError
+		if !errors.Is(err, context.Canceled) || !errors.As(err, &kErr) || kErr.Key != "db:password" {
+			t.Errorf("cancelled context did not return context.Canceled: %v", err)
+		}
+	}
+	{
+		rec, err := s.GetValueCtx(ctx, "local")
+		assertVal(t, rec, err, "value")
+	}
+	assert(t, atomic.LoadInt32(&remote.calls), int32(3))
+}
+
 func TestGetString(t *testing.T) {
 	s := makeDefSection()
 
@@ -76,7 +162,7 @@ func TestGetString(t *testing.T) {
 		if err == nil {
 			t.Error("recovering returned no error")
 		}
-		if err != ErrNil {
+		if !errors.Is(err, ErrNil) {
 			t.Error("recovering returned not the expected error ErrNil")
 		}
 	}
@@ -100,6 +186,32 @@ func TestGetInt(t *testing.T) {
 			t.Errorf("recovering did not returned an error")
 		}
 	}
+	{
+		s := makeSection(ConfigMap{
+			"int":      "10",
+			"integral": "10.0",
+			"fraction": "10.5",
+			"huge":     "1e30",
+		})
+		rec, err := s.GetInt("int")
+		assertVal(t, rec, err, 10)
+		rec, err = s.GetInt("integral")
+		assertVal(t, rec, err, 10)
+		if _, err = s.GetInt("fraction"); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("fractional string did not return ErrInvalidType: %v", err)
+		}
+		if _, err = s.GetInt("huge"); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("out of range string did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		s := makeSection(ConfigMap{"json": 1e6, "fraction": 10.5})
+		rec, err := s.GetInt("json")
+		assertVal(t, rec, err, 1000000)
+		if _, err = s.GetInt("fraction"); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("fractional float did not return ErrInvalidType: %v", err)
+		}
+	}
 	{
 		_, err := s.GetInt("a:b")
 		if err == nil {
@@ -117,7 +229,7 @@ func TestGetInt(t *testing.T) {
 		if err == nil {
 			t.Error("recovering returned no error")
 		}
-		if err != ErrNil {
+		if !errors.Is(err, ErrNil) {
 			t.Error("recovering returned not the expected error ErrNil")
 		}
 	}
@@ -161,7 +273,7 @@ func TestGetBool(t *testing.T) {
 		if err == nil {
 			t.Error("recovering returned no error")
 		}
-		if err != ErrNil {
+		if !errors.Is(err, ErrNil) {
 			t.Error("recovering returned not the expected error ErrNil")
 		}
 	}
@@ -205,18 +317,1117 @@ func TestGetFloat64(t *testing.T) {
 		if err == nil {
 			t.Error("recovering returned no error")
 		}
-		if err != ErrNil {
+		if !errors.Is(err, ErrNil) {
 			t.Error("recovering returned not the expected error ErrNil")
 		}
 	}
 }
 
-// --------------------------------------------------------------------------
-// --- HELPERS
+func TestGetFloat32(t *testing.T) {
+	s := makeDefSection()
+
+	{
+		rec, err := s.GetFloat32("a:f")
+		if err != nil {
+			t.Errorf("recovering returned error: %s", err.Error())
+		}
+		if rec != 3.1415 {
+			t.Errorf(`recovered value (%+v) was not like expected (3.1415)`, rec)
+		}
+	}
+	{
+		s := makeSection(ConfigMap{"f": 1e40})
+		_, err := s.GetFloat32("f")
+		if err == nil {
+			t.Errorf("recovering of overflowing value did not returned an error")
+		}
+	}
+	{
+		_, err := s.GetFloat32("a:s")
+		if err == nil {
+			t.Errorf("recovering did not returned an error")
+		}
+	}
+	{
+		_, err := s.GetFloat32("a:none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetSizedInts(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"i8":    int8(127),
+		"i8o":   128,
+		"i8u":   -129,
+		"i16":   -32768,
+		"i16o":  32768,
+		"i32":   "2147483647",
+		"i32o":  2147483648,
+		"float": 1.5,
+	})
+
+	{
+		rec, err := s.GetInt8("i8")
+		assertVal(t, rec, err, int8(127))
+	}
+	{
+		_, err := s.GetInt8("i8o")
+		if err == nil {
+			t.Error("recovering of overflowing int8 did not returned an error")
+		}
+	}
+	{
+		_, err := s.GetInt8("i8u")
+		if err == nil {
+			t.Error("recovering of underflowing int8 did not returned an error")
+		}
+	}
+	{
+		rec, err := s.GetInt16("i16")
+		assertVal(t, rec, err, int16(-32768))
+	}
+	{
+		_, err := s.GetInt16("i16o")
+		if err == nil {
+			t.Error("recovering of overflowing int16 did not returned an error")
+		}
+	}
+	{
+		rec, err := s.GetInt32("i32")
+		assertVal(t, rec, err, int32(2147483647))
+	}
+	{
+		_, err := s.GetInt32("i32o")
+		if err == nil {
+			t.Error("recovering of overflowing int32 did not returned an error")
+		}
+	}
+	{
+		_, err := s.GetInt8("float")
+		if err == nil {
+			t.Error("recovering of float did not returned an error")
+		}
+	}
+	{
+		_, err := s.GetInt8("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestIntOverflowPolicy(t *testing.T) {
+	m := ConfigMap{
+		"i8o":  200,
+		"i8u":  -200,
+		"i16o": "40000",
+		"i32o": int64(4294967297),
+		"huge": "99999999999999999999",
+		"ok":   100,
+	}
+	withPolicy := func(policy IntOverflowPolicy) *section {
+		s := makeSection(m)
+		s.opts = &sectionOptions{overflowPolicy: policy}
+		return s
+	}
+
+	{
+		s := withPolicy(OverflowError)
+		if _, err := s.GetInt8("i8o"); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("overflowing int8 did not return ErrInvalidType: %v", err)
+		}
+		rec, err := s.GetInt8("ok")
+		assertVal(t, rec, err, int8(100))
+	}
+	{
+		s := withPolicy(OverflowSaturate)
+		rec, err := s.GetInt8("i8o")
+		assertVal(t, rec, err, int8(127))
+		rec, err = s.GetInt8("i8u")
+		assertVal(t, rec, err, int8(-128))
+		rec16, err := s.GetInt16("i16o")
+		assertVal(t, rec16, err, int16(32767))
+		rec32, err := s.GetInt32("i32o")
+		assertVal(t, rec32, err, int32(2147483647))
+		rec32, err = s.GetInt32("huge")
+		assertVal(t, rec32, err, int32(2147483647))
+	}
+	{
+		s := withPolicy(OverflowWrap)
+		rec, err := s.GetInt8("i8o")
+		assertVal(t, rec, err, int8(-56))
+		rec, err = s.GetInt8("i8u")
+		assertVal(t, rec, err, int8(56))
+		rec16, err := s.GetInt16("i16o")
+		assertVal(t, rec16, err, int16(-25536))
+		rec32, err := s.GetInt32("i32o")
+		assertVal(t, rec32, err, int32(1))
+		if _, err = s.GetInt32("huge"); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("value exceeding int64 did not return ErrInvalidType: %v", err)
+		}
+	}
+
+	c, err := NewBuilder().
+		AddMap(m).
+		WithIntOverflowPolicy(OverflowSaturate).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		rec, err := c.GetInt8("i8o")
+		assertVal(t, rec, err, int8(127))
+	}
+}
+
+func TestGetValueCopy(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"l": []interface{}{1, ConfigMap{"a": 1}},
+		"m": ConfigMap{
+			"a": 1,
+		},
+		"s": []string{"a", "b"},
+	})
+
+	{
+		rec, err := s.GetValue("l")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		l := rec.([]interface{})
+		l[0] = 2
+		l[1].(ConfigMap)["a"] = 2
+
+		assert(t, s.m["l"].([]interface{})[0], 1)
+		assert(t, s.m["l"].([]interface{})[1].(ConfigMap)["a"], 1)
+	}
+	{
+		rec, err := s.GetValue("m")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		rec.(ConfigMap)["a"] = 2
+		rec.(ConfigMap)["b"] = 2
+
+		assert(t, s.m["m"].(ConfigMap)["a"], 1)
+		if _, ok := s.m["m"].(ConfigMap)["b"]; ok {
+			t.Error("added key was set in internal map")
+		}
+	}
+	{
+		rec, err := s.GetValue("s")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		rec.([]string)[0] = "c"
+
+		assert(t, s.m["s"].([]string)[0], "a")
+	}
+}
+
+func TestGetPercent(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"percent":  "75%",
+		"fraction": 0.75,
+		"string":   "0.5",
+		"zero":     0,
+		"over":     "120%",
+		"bare":     75,
+		"invalid":  "much",
+	})
+
+	{
+		rec, err := s.GetPercent("percent")
+		assertVal(t, rec, err, 0.75)
+	}
+	{
+		rec, err := s.GetPercent("fraction")
+		assertVal(t, rec, err, 0.75)
+	}
+	{
+		rec, err := s.GetPercent("string")
+		assertVal(t, rec, err, 0.5)
+	}
+	{
+		rec, err := s.GetPercent("zero")
+		assertVal(t, rec, err, 0.0)
+	}
+	for _, key := range []string{"over", "bare", "invalid"} {
+		_, err := s.GetPercent(key)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("recovering %s did not return ErrInvalidType: %v", key, err)
+		}
+	}
+	{
+		_, err := s.GetPercent("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetUUID(t *testing.T) {
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	s := makeSection(ConfigMap{
+		"id":      id.String(),
+		"invalid": "not-a-uuid",
+		"number":  42,
+	})
+
+	{
+		rec, err := s.GetUUID("id")
+		assertVal(t, rec, err, id)
+	}
+	for _, key := range []string{"invalid", "number"} {
+		_, err := s.GetUUID(key)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("recovering %s did not return ErrInvalidType: %v", key, err)
+		}
+	}
+	{
+		_, err := s.GetUUID("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+
+	def := uuid.New()
+	assertVal(t, s.GetUUIDOrDef("id", def), nil, id)
+	assertVal(t, s.GetUUIDOrDef("invalid", def), nil, def)
+	assertVal(t, s.GetUUIDOrDef("none", def), nil, def)
+}
+
+func TestGetDurationSeconds(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"int":      30,
+		"float":    1.5,
+		"numeric":  "30",
+		"string":   "30s",
+		"compound": "1m30s",
+		"duration": 2 * time.Second,
+		"invalid":  "soon",
+		"overflow": 1e300,
+	})
+
+	{
+		rec, err := s.GetDurationSeconds("int")
+		assertVal(t, rec, err, 30*time.Second)
+	}
+	{
+		rec, err := s.GetDurationSeconds("float")
+		assertVal(t, rec, err, 1500*time.Millisecond)
+	}
+	{
+		rec, err := s.GetDurationSeconds("numeric")
+		assertVal(t, rec, err, 30*time.Second)
+	}
+	{
+		rec, err := s.GetDurationSeconds("string")
+		assertVal(t, rec, err, 30*time.Second)
+	}
+	{
+		rec, err := s.GetDurationSeconds("compound")
+		assertVal(t, rec, err, 90*time.Second)
+	}
+	{
+		rec, err := s.GetDurationSeconds("duration")
+		assertVal(t, rec, err, 2*time.Second)
+	}
+	for _, key := range []string{"invalid", "overflow"} {
+		_, err := s.GetDurationSeconds(key)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("recovering %s did not return ErrInvalidType: %v", key, err)
+		}
+	}
+	{
+		_, err := s.GetDurationSeconds("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetBoolExtended(t *testing.T) {
+	accepted := map[string]bool{
+		"yes": true, "Y": true, "ON": true, "Enabled": true,
+		"true": true, "T": true, "1": true,
+		"no": false, "n": false, "Off": false, "DISABLED": false,
+		"false": false, "F": false, "0": false,
+	}
+
+	m := ConfigMap{"bool": false, "invalid": "maybe", "num": 1}
+	for k := range accepted {
+		m[k] = k
+	}
+	s := makeSection(m)
+
+	for k, expected := range accepted {
+		rec, err := s.GetBoolExtended(k)
+		assertVal(t, rec, err, expected)
+	}
+	{
+		rec, err := s.GetBoolExtended("bool")
+		assertVal(t, rec, err, false)
+	}
+	{
+		rec, err := s.GetBoolExtended("num")
+		assertVal(t, rec, err, true)
+	}
+	{
+		_, err := s.GetBoolExtended("invalid")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("invalid value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetBool("yes")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("strict getter accepted extended value: %v", err)
+		}
+	}
+	{
+		_, err := s.GetBoolExtended("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetTypedMap(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"m": ConfigMap{
+			"i":     "10",
+			"f":     "1.5",
+			"b":     "true",
+			"s":     "text",
+			"n":     5,
+			"inner": ConfigMap{"b": "FALSE"},
+			"list":  []interface{}{"1", "a"},
+		},
+		"s": "text",
+	})
+
+	{
+		rec, err := s.GetTypedMap("m")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		assert(t, rec["i"], 10)
+		assert(t, rec["f"], 1.5)
+		assert(t, rec["b"], true)
+		assert(t, rec["s"], "text")
+		assert(t, rec["n"], 5)
+		assert(t, rec["inner"].(ConfigMap)["b"], false)
+		assertSlice(t, rec["list"], nil, []interface{}{1, "a"})
+	}
+	{
+		rec, err := s.GetStringMap("m")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		assert(t, rec["i"], "10")
+	}
+	{
+		_, err := s.GetTypedMap("s")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("scalar value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetTypedMap("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetSlices(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"strings": []interface{}{"a", 1, true},
+		"ints":    []interface{}{1, "2", 3.0},
+		"floats":  []interface{}{1.5, "2.5", 3},
+		"bools":   []interface{}{true, "false", 1},
+		"typed":   []string{"x", "y"},
+		"mixed":   []interface{}{1, "a"},
+		"scalar":  "a",
+	})
+
+	{
+		rec, err := s.GetStringSlice("strings")
+		assertSlice(t, rec, err, []string{"a", "1", "true"})
+	}
+	{
+		rec, err := s.GetStringSlice("typed")
+		assertSlice(t, rec, err, []string{"x", "y"})
+	}
+	{
+		rec, err := s.GetIntSlice("ints")
+		assertSlice(t, rec, err, []int{1, 2, 3})
+	}
+	{
+		rec, err := s.GetFloat64Slice("floats")
+		assertSlice(t, rec, err, []float64{1.5, 2.5, 3})
+	}
+	{
+		rec, err := s.GetBoolSlice("bools")
+		assertSlice(t, rec, err, []bool{true, false, true})
+	}
+	{
+		_, err := s.GetIntSlice("mixed")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("mixed slice did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetStringSlice("scalar")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("scalar value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetStringSlice("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetNested(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"metadata": `{"a": 1, "labels": {"env": "prod"}, "hosts": ["x", "y"]}`,
+		"array":    `[1, 2]`,
+		"invalid":  `{"a":`,
+		"number":   1,
+		"section":  ConfigMap{"a": 1},
+	})
+
+	{
+		n, err := s.GetNested("metadata")
+		if err != nil {
+			t.Fatalf("recovering returned error: %s", err.Error())
+		}
+		{
+			rec, err := n.GetInt("a")
+			assertVal(t, rec, err, 1)
+		}
+		{
+			rec, err := n.GetString("labels:env")
+			assertVal(t, rec, err, "prod")
+		}
+		{
+			rec, err := n.GetString("hosts:1")
+			assertVal(t, rec, err, "y")
+		}
+	}
+	for _, key := range []string{"array", "invalid", "number", "section"} {
+		_, err := s.GetNested(key)
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("value of %q did not return ErrInvalidType: %v", key, err)
+		}
+	}
+	{
+		_, err := s.GetNested("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetLines(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"allowlist": "# allowed hosts\r\nexample.com\n\n  api.example.com  \n\t# internal\nlocalhost\n",
+		"sequence":  []interface{}{"one", "two"},
+		"section":   ConfigMap{"a": "one"},
+	})
+
+	{
+		rec, err := s.GetLines("allowlist")
+		assertSlice(t, rec, err, []string{"example.com", "api.example.com", "localhost"})
+	}
+	{
+		_, err := s.GetLines("sequence")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("slice value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetLines("section")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("section value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetLines("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetStringSliceFlexible(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"scalar":   "one",
+		"number":   1,
+		"sequence": []interface{}{"one", "two"},
+		"single":   []interface{}{"one"},
+		"section":  ConfigMap{"a": "one"},
+	})
+
+	{
+		rec, err := s.GetStringSliceFlexible("scalar")
+		assertSlice(t, rec, err, []string{"one"})
+	}
+	{
+		rec, err := s.GetStringSliceFlexible("single")
+		assertSlice(t, rec, err, []string{"one"})
+	}
+	{
+		rec, err := s.GetStringSliceFlexible("number")
+		assertSlice(t, rec, err, []string{"1"})
+	}
+	{
+		rec, err := s.GetStringSliceFlexible("sequence")
+		assertSlice(t, rec, err, []string{"one", "two"})
+	}
+	{
+		_, err := s.GetStringSliceFlexible("section")
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("section value did not return ErrInvalidType: %v", err)
+		}
+	}
+	{
+		_, err := s.GetStringSliceFlexible("none")
+		if !errors.Is(err, ErrNil) {
+			t.Error("recovering returned not the expected error ErrNil")
+		}
+	}
+}
+
+func TestGetSlicesOrDef(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"strings": []interface{}{"a", "b"},
+		"ints":    []interface{}{1, 2},
+		"floats":  []interface{}{1.5, 2.5},
+		"bools":   []interface{}{true, false},
+		"mixed":   []interface{}{1, "a"},
+	})
+
+	assertSlice(t, s.GetStringSliceOrDef("strings", []string{"def"}), nil, []string{"a", "b"})
+	assertSlice(t, s.GetStringSliceOrDef("none", []string{"def"}), nil, []string{"def"})
+
+	assertSlice(t, s.GetIntSliceOrDef("ints", []int{0}), nil, []int{1, 2})
+	assertSlice(t, s.GetIntSliceOrDef("none", []int{0}), nil, []int{0})
+	assertSlice(t, s.GetIntSliceOrDef("mixed", []int{0}), nil, []int{0})
+
+	assertSlice(t, s.GetFloat64SliceOrDef("floats", []float64{0}), nil, []float64{1.5, 2.5})
+	assertSlice(t, s.GetFloat64SliceOrDef("mixed", []float64{0}), nil, []float64{0})
+
+	assertSlice(t, s.GetBoolSliceOrDef("bools", []bool{false}), nil, []bool{true, false})
+	assertSlice(t, s.GetBoolSliceOrDef("none", []bool{false}), nil, []bool{false})
+}
+
+func TestOrDefInvalidTypeHandler(t *testing.T) {
+	var reported []error
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"i":   1,
+			"s":   "test123",
+			"arr": []interface{}{"a"},
+		},
+	})
+	s.opts = &sectionOptions{
+		typeErrHandler: func(err error) {
+			reported = append(reported, err)
+		},
+	}
+
+	assertVal(t, s.GetIntOrDef("a:none", 2), nil, 2)
+	if len(reported) != 0 {
+		t.Errorf("missing key was reported: %v", reported)
+	}
+
+	assertVal(t, s.GetIntOrDef("a:i", 2), nil, 1)
+	if len(reported) != 0 {
+		t.Errorf("valid value was reported: %v", reported)
+	}
+
+	assertVal(t, s.GetIntOrDef("a:s", 2), nil, 2)
+	assertVal(t, s.GetBoolOrDef("a:s", true), nil, true)
+	assertSlice(t, s.GetIntSliceOrDef("a:arr", []int{2}), nil, []int{2})
+	if len(reported) != 3 {
+		t.Fatalf("invalid types were not reported: %v", reported)
+	}
+	for _, err := range reported {
+		if !errors.Is(err, ErrInvalidType) {
+			t.Errorf("reported error is not ErrInvalidType: %v", err)
+		}
+	}
+
+	var kErr *KeyError
+	if !errors.As(reported[0], &kErr) || kErr.Key != "a:s" {
+		t.Errorf("reported error does not carry the key: %v", reported[0])
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"db": ConfigMap{
+			"host": "db.example.com",
+			"pool": ConfigMap{"min": 1},
+		},
+	})
+
+	d := s.GetSection("db").WithDefaults(ConfigMap{
+		"host": "localhost",
+		"port": 5432,
+		"pool": ConfigMap{"size": 10},
+	})
+
+	{
+		rec, err := d.GetString("host")
+		assertVal(t, rec, err, "db.example.com")
+	}
+	{
+		rec, err := d.GetInt("port")
+		assertVal(t, rec, err, 5432)
+	}
+	{
+		rec, err := d.GetSection("pool").GetInt("size")
+		assertVal(t, rec, err, 10)
+	}
+	{
+		rec, err := d.WithPrefix("pool").GetInt("size")
+		assertVal(t, rec, err, 10)
+	}
+	{
+		rec, err := d.GetSectionPath("pool").GetInt("size")
+		assertVal(t, rec, err, 10)
+	}
+	{
+		rec, err := d.GetSectionPath("pool").GetInt("min")
+		assertVal(t, rec, err, 1)
+	}
+	assertSlice(t, d.Keys(), nil, []string{"host", "pool", "port"})
+	assertSlice(t, d.SectionNames(), nil, []string{"pool"})
+	assertSlice(t, d.GetSection("pool").Keys(), nil, []string{"min", "size"})
+	assertSlice(t, d.GetSectionPath("pool").Keys(), nil, []string{"min", "size"})
+	{
+		_, err := d.GetString("user")
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("missing key without default did not return ErrNil: %v", err)
+		}
+	}
+	{
+		if !d.GetSection("none").IsNil() {
+			t.Error("missing section without default was not nil")
+		}
+	}
+	{
+		_, err := s.GetInt("db:port")
+		if !errors.Is(err, ErrNil) {
+			t.Error("defaults leaked into the original section")
+		}
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"b": ConfigMap{
+				"s": "test",
+				"i": 1,
+				"c": ConfigMap{"f": 1.5},
+			},
+		},
+	})
+
+	cur := s.WithPrefix("a:b")
+	{
+		rec, err := cur.GetString("s")
+		assertVal(t, rec, err, "test")
+	}
+	{
+		rec, err := cur.GetInt("i")
+		assertVal(t, rec, err, 1)
+	}
+	{
+		rec, err := cur.GetFloat64("c:f")
+		assertVal(t, rec, err, 1.5)
+	}
+	{
+		rec, err := cur.WithPrefix("c").GetFloat64("f")
+		assertVal(t, rec, err, 1.5)
+	}
+	{
+		rec, err := cur.GetSection("c").GetFloat64("f")
+		assertVal(t, rec, err, 1.5)
+	}
+	{
+		_, err := cur.GetValue("none")
+		var keyErr *KeyError
+		if !errors.Is(err, ErrNil) || !errors.As(err, &keyErr) || keyErr.Key != "a:b:none" {
+			t.Errorf("missing key error (%+v) was not like expected", err)
+		}
+	}
+
+	missing := s.WithPrefix("x")
+	if missing.IsNil() {
+		t.Error("cursor for missing prefix was nil")
+	}
+	s.m["x"] = ConfigMap{"v": 2}
+	s.children = nil
+	{
+		rec, err := missing.GetInt("v")
+		assertVal(t, rec, err, 2)
+	}
+}
+
+func TestArrayIndexing(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"list": []interface{}{
+			"a",
+			map[string]interface{}{"v": 1},
+			map[interface{}]interface{}{"v": 2},
+		},
+		"typed": []string{"x", "y"},
+	})
+
+	{
+		v, err := s.GetString("list:0")
+		assertVal(t, v, err, "a")
+	}
+	{
+		v, err := s.GetInt("list:1:v")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := s.GetSection("list:2").GetInt("v")
+		assertVal(t, v, err, 2)
+	}
+	{
+		v, err := s.GetString("typed:1")
+		assertVal(t, v, err, "y")
+	}
+	for _, key := range []string{"list:3", "list:-1", "list:x", "list:0:v"} {
+		_, err := s.GetValue(key)
+		if !errors.Is(err, ErrNil) {
+			t.Errorf("recovering %s did not return ErrNil: %v", key, err)
+		}
+	}
+}
+
+func TestGetSectionCache(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"b": ConfigMap{
+				"c": 1,
+			},
+		},
+	})
+
+	first := s.GetSection("a:b")
+	second := s.GetSection("a:b")
+	if first != second {
+		t.Error("repeated GetSection did not return the cached section")
+	}
+	if first.(*section).mtx != s.mtx {
+		t.Error("child section does not share the mutex of its parent")
+	}
+}
+
+func BenchmarkGetStringTyped(b *testing.B) {
+	s := makeSection(ConfigMap{
+		"i": "12345",
+		"b": "true",
+		"f": "3.1415",
+	})
+	ckInt, ckBool, ckFloat := CompileKey("i"), CompileKey("b"), CompileKey("f")
+
+	b.Run("int", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.GetIntCompiled(ckInt)
+		}
+	})
+
+	b.Run("bool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.GetBoolCompiled(ckBool)
+		}
+	})
+
+	b.Run("float64", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.GetFloat64Compiled(ckFloat)
+		}
+	})
+}
+
+func BenchmarkGetSection(b *testing.B) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{
+			"b": ConfigMap{
+				"c": 1,
+			},
+		},
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.GetSection("a:b")
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"a": ConfigMap{"b": "existing"},
+		"c": "value",
+	})
+
+	var calls int32
+	compute := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "computed", nil
+	}
+
+	{
+		v, err := s.GetOrSet("a:b", compute)
+		assertVal(t, v, err, "existing")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := s.GetOrSet("x:y", compute)
+			assertVal(t, v, err, "computed")
+		}()
+	}
+	wg.Wait()
+	assert(t, atomic.LoadInt32(&calls), int32(1))
+
+	{
+		v, err := s.GetString("x:y")
+		assertVal(t, v, err, "computed")
+	}
+	{
+		v, err := s.WithPrefix("x").GetOrSet("z", compute)
+		assertVal(t, v, err, "computed")
+		v, err = s.GetString("x:z")
+		assertVal(t, v, err, "computed")
+	}
+	{
+		cErr := errors.New("compute failed")
+		_, err := s.GetOrSet("e", func() (interface{}, error) {
+			return nil, cErr
+		})
+		if !errors.Is(err, cErr) {
+			t.Errorf("compute error was not returned: %v", err)
+		}
+		if _, err = s.GetValue("e"); !errors.Is(err, ErrNil) {
+			t.Errorf("failed compute stored a value: %v", err)
+		}
+	}
+	{
+		keys := s.Keys()
+		_, err := s.GetOrSet("f:g:h", func() (interface{}, error) {
+			return nil, errors.New("compute failed")
+		})
+		if err == nil {
+			t.Error("compute error was not returned")
+		}
+		assertSlice(t, s.Keys(), nil, keys)
+		assertSlice(t, s.GetSection("a").Keys(), nil, []string{"b"})
+	}
+	{
+		_, err := s.GetOrSet("c:d", compute)
+		var kerr *KeyError
+		if !errors.As(err, &kerr) || kerr.Kind != KindNotSection {
+			t.Errorf("value on path did not return KindNotSection: %v", err)
+		}
+	}
+}
+
+func TestGetOrSetCaseFold(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"Server": ConfigMap{"Host": "localhost"},
+	})
+	s.opts = &sectionOptions{caseFold: true}
+
+	{
+		v, err := s.GetString("server:host")
+		assertVal(t, v, err, "localhost")
+	}
+
+	compute := func() (interface{}, error) {
+		return 8080, nil
+	}
+	for _, key := range []string{"Server:Port", "Timeout", "Db:Host"} {
+		if _, err := s.GetOrSet(key, compute); err != nil {
+			t.Fatalf("get or set of %q failed: %s", key, err.Error())
+		}
+	}
+
+	for _, key := range []string{"server:port", "timeout", "db:host"} {
+		v, err := s.GetInt(key)
+		assertVal(t, v, err, 8080)
+	}
+
+	{
+		v, err := s.GetOrSet("server:host", compute)
+		assertVal(t, v, err, "localhost")
+	}
+	if _, err := s.GetOrSet("server:user", compute); err != nil {
+		t.Fatalf("get or set failed: %s", err.Error())
+	}
+	if _, ok := s.m["server"]; ok {
+		t.Error("duplicate section was created for differently cased key")
+	}
+	{
+		v, err := s.GetInt("Server:user")
+		assertVal(t, v, err, 8080)
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"hosts": ConfigMap{
+			"localhost:8080": ConfigMap{
+				"name": "local",
+			},
+		},
+		"a:b": "literal",
+	})
+
+	{
+		v, err := s.GetValuePath("hosts", "localhost:8080", "name")
+		assertVal(t, v, err, "local")
+	}
+	{
+		v, err := s.GetValuePath("a:b")
+		assertVal(t, v, err, "literal")
+	}
+	{
+		v, err := s.GetSectionPath("hosts", "localhost:8080").GetString("name")
+		assertVal(t, v, err, "local")
+	}
+	{
+		v, err := s.WithPrefix("hosts").GetValuePath("localhost:8080", "name")
+		assertVal(t, v, err, "local")
+	}
+	{
+		v, err := s.WithPrefix("hosts").GetSectionPath("localhost:8080").GetString("name")
+		assertVal(t, v, err, "local")
+	}
+	if sec := s.GetSectionPath("hosts", "localhost"); sec != nil && !sec.IsNil() {
+		t.Error("missing section was not nil")
+	}
+	if _, err := s.GetValuePath(); !errors.Is(err, ErrNil) {
+		t.Errorf("empty path did not return ErrNil: %v", err)
+	}
+}
+
+func TestSectionNames(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"tenants": ConfigMap{
+			"zeta":  ConfigMap{"name": "z"},
+			"alpha": map[string]interface{}{"name": "a"},
+			"count": 2,
+			"list":  []interface{}{"a"},
+		},
+		"name": "root",
+	})
+
+	assertSlice(t, s.GetSection("tenants").SectionNames(), nil, []string{"alpha", "zeta"})
+	assertSlice(t, s.GetSection("tenants").Keys(), nil, []string{"alpha", "count", "list", "zeta"})
+	assertSlice(t, s.SectionNames(), nil, []string{"tenants"})
+	assertSlice(t, s.Keys(), nil, []string{"name", "tenants"})
+	assertSlice(t, s.WithPrefix("tenants").SectionNames(), nil, []string{"alpha", "zeta"})
+	assertSlice(t, s.GetSection("tenants:zeta").SectionNames(), nil, []string{})
+
+	if names := s.WithPrefix("missing").SectionNames(); names != nil {
+		t.Errorf("names (%v) of missing section were not nil", names)
+	}
+}
+
+func TestEmptyKey(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"":  "empty",
+		"a": ConfigMap{"b": 1},
+	})
+
+	if sec := s.GetSection(""); sec != Section(s) {
+		t.Error("empty key did not return the section itself")
+	}
+	{
+		v, err := s.GetSection("a").GetSection("").GetInt("b")
+		assertVal(t, v, err, 1)
+	}
+	{
+		v, err := s.WithPrefix("a").GetSection("").GetInt("b")
+		assertVal(t, v, err, 1)
+	}
+
+	for _, sec := range []Section{s, s.GetSection("a"), s.WithPrefix("a")} {
+		_, err := sec.GetValue("")
+		var kerr *KeyError
+		if !errors.Is(err, ErrNil) || !errors.As(err, &kerr) || kerr.Kind != KindKeyMissing {
+			t.Errorf("empty key did not return ErrNil: %v", err)
+		}
+		if _, err = sec.GetString(""); !errors.Is(err, ErrNil) {
+			t.Errorf("empty key did not return ErrNil: %v", err)
+		}
+	}
+}
+
+func TestGetStringSliceOptions(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"tags": []interface{}{" a", "b ", "", "a", "  ", "c", "b"},
+	})
+
+	{
+		rec, err := s.GetStringSlice("tags")
+		assertSlice(t, rec, err, []string{" a", "b ", "", "a", "  ", "c", "b"})
+	}
+	{
+		rec, err := s.GetStringSlice("tags", WithTrimSpace())
+		assertSlice(t, rec, err, []string{"a", "b", "", "a", "", "c", "b"})
+	}
+	{
+		rec, err := s.GetStringSlice("tags", WithDropEmpty())
+		assertSlice(t, rec, err, []string{" a", "b ", "a", "  ", "c", "b"})
+	}
+	{
+		rec, err := s.GetStringSlice("tags", WithTrimSpace(), WithDropEmpty(), WithDeduplication())
+		assertSlice(t, rec, err, []string{"a", "b", "c"})
+	}
+	{
+		rec, err := s.GetStringSliceFlexible("tags", WithDeduplication())
+		assertSlice(t, rec, err, []string{" a", "b ", "", "a", "  ", "c", "b"})
+	}
+}
+
+// --------------------------------------------------------------------------
+// --- HELPERS
+
+func assertSlice(t *testing.T, val interface{}, err error, expected interface{}) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("get value errored: %s", err.Error())
+	} else if !reflect.DeepEqual(val, expected) {
+		t.Errorf("value (%+v) was not like expected (%+v)", val, expected)
+	}
+}
 
 func makeSection(m ConfigMap) *section {
 	return &section{
-		mtx: sync.Mutex{},
+		mtx: &sync.Mutex{},
 		m:   m,
 	}
 }
@@ -0,0 +1,77 @@
+package configoration
+
+import "strings"
+
+// SliceOption configures how GetStringSlice
+// post-processes the elements of a slice.
+type SliceOption func(o *sliceOptions)
+
+type sliceOptions struct {
+	trimSpace   bool
+	dropEmpty   bool
+	deduplicate bool
+}
+
+// WithTrimSpace removes leading and trailing
+// white space from each element.
+func WithTrimSpace() SliceOption {
+	return func(o *sliceOptions) {
+		o.trimSpace = true
+	}
+}
+
+// WithDropEmpty removes empty elements. When
+// combined with WithTrimSpace, elements which
+// only consist of white space are removed as
+// well.
+func WithDropEmpty() SliceOption {
+	return func(o *sliceOptions) {
+		o.dropEmpty = true
+	}
+}
+
+// WithDeduplication removes all but the first
+// occurrence of each element while preserving
+// the order of the elements.
+func WithDeduplication() SliceOption {
+	return func(o *sliceOptions) {
+		o.deduplicate = true
+	}
+}
+
+// applySliceOptions returns vs processed by
+// the passed options.
+func applySliceOptions(vs []string, opts []SliceOption) []string {
+	if len(opts) == 0 {
+		return vs
+	}
+
+	var o sliceOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var seen map[string]struct{}
+	if o.deduplicate {
+		seen = make(map[string]struct{}, len(vs))
+	}
+
+	res := vs[:0]
+	for _, v := range vs {
+		if o.trimSpace {
+			v = strings.TrimSpace(v)
+		}
+		if o.dropEmpty && v == "" {
+			continue
+		}
+		if seen != nil {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+		}
+		res = append(res, v)
+	}
+
+	return res
+}
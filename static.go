@@ -1,5 +1,7 @@
 package configoration
 
+import "time"
+
 const (
 	// Version describes the current
 	// package version.
@@ -8,4 +10,9 @@ const (
 	// Delimiter describes the split string
 	// used to split sections.
 	Delimiter = ":"
+
+	// DefaultWatchInterval is the interval in
+	// which BuildAndWatch reloads the sources
+	// if no refresh interval is set.
+	DefaultWatchInterval = time.Second
 )
@@ -0,0 +1,51 @@
+package configoration
+
+// TransformFunc transforms the values of a
+// source before they are merged.
+type TransformFunc func(m ConfigMap) (ConfigMap, error)
+
+// transformProvider wraps a Provider and applies
+// a TransformFunc to the values it returns.
+type transformProvider struct {
+	Provider
+	transform TransformFunc
+}
+
+func (p *transformProvider) GetMap() (map[string]interface{}, error) {
+	m, err := p.Provider.GetMap()
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	res, err := p.transform(ConfigMap(m))
+	if err != nil {
+		if p.Optional() {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (p *transformProvider) Name() string {
+	return providerName(p.Provider)
+}
+
+func (p *transformProvider) Optional() bool {
+	return isOptional(p.Provider)
+}
+
+func (p *transformProvider) FileName() string {
+	if fp, ok := p.Provider.(FileProvider); ok {
+		return fp.FileName()
+	}
+	return ""
+}
+
+func (p *transformProvider) unwrap() Provider {
+	return p.Provider
+}
@@ -0,0 +1,150 @@
+package configoration
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const validateTag = "validate"
+
+func (s *section) BindSchema(key string, target interface{}) error {
+	if err := s.UnmarshalKey(key, target); err != nil {
+		return err
+	}
+
+	return validateValue(key, reflect.ValueOf(target))
+}
+
+// ValidateStruct checks all fields of the passed
+// struct, or pointer to a struct, against the
+// rules of their `validate` tag and returns all
+// violations joined via errors.Join. Each
+// violation is a *ValidationError whose key is
+// built from the `config` tags or names of the
+// fields like for UnmarshalKey.
+//
+// Supported rules are "required", "min=n" and
+// "max=n", which compare numbers by value and
+// strings, slices and maps by length, and
+// "oneof=a b c".
+func ValidateStruct(v interface{}) error {
+	return validateValue("", reflect.ValueOf(v))
+}
+
+// validateValue validates rv, which may be a
+// pointer to a struct, and returns all
+// violations joined.
+func validateValue(key string, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return errors.Join(validateStruct(key, rv)...)
+}
+
+func validateStruct(key string, rv reflect.Value) (errs []error) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup(structTag)
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && !ok && field.Type.Kind() == reflect.Struct {
+			errs = append(errs, validateStruct(key, rv.Field(i))...)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if !ok || name == "" {
+			name = field.Name
+		}
+
+		fieldKey := joinPath(key, name)
+		fv := rv.Field(i)
+
+		if rules := field.Tag.Get(validateTag); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				if err := checkRule(fieldKey, strings.TrimSpace(rule), fv); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			errs = append(errs, validateStruct(fieldKey, fv)...)
+		}
+	}
+
+	return errs
+}
+
+// checkRule returns a *ValidationError if fv
+// violates rule.
+func checkRule(key, rule string, fv reflect.Value) error {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	var valid bool
+	switch name {
+	case "required":
+		valid = !fv.IsZero()
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Errorf("key %q: invalid validation rule %q", key, rule)
+		}
+		n, ok := measure(fv)
+		if !ok {
+			return fmt.Errorf("key %q: rule %q is not applicable to %s", key, rule, fv.Type())
+		}
+		valid = (name == "min" && n >= limit) || (name == "max" && n <= limit)
+	case "oneof":
+		v := toString(fv.Interface())
+		for _, opt := range strings.Fields(arg) {
+			if v == opt {
+				valid = true
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("key %q: unknown validation rule %q", key, rule)
+	}
+
+	if !valid {
+		return &ValidationError{Key: key, Rule: rule}
+	}
+	return nil
+}
+
+// measure returns the numeric value of fv or,
+// for strings, slices and maps, its length.
+func measure(fv reflect.Value) (float64, bool) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len()), true
+	}
+	return 0, false
+}
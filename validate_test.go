@@ -0,0 +1,86 @@
+package configoration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validateDB struct {
+	Host string `config:"host" validate:"required"`
+	Port int    `config:"port" validate:"min=1,max=65535"`
+}
+
+type validateConfig struct {
+	Mode string     `config:"mode" validate:"oneof=dev prod"`
+	Tags []string   `config:"tags" validate:"max=2"`
+	DB   validateDB `config:"db"`
+}
+
+func TestBindSchema(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"app": ConfigMap{
+			"mode": "staging",
+			"tags": []interface{}{"a", "b"},
+			"db": ConfigMap{
+				"host": "",
+				"port": 70000,
+			},
+		},
+	})
+
+	var cfg validateConfig
+	err := s.BindSchema("app", &cfg)
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Fatalf("violations did not return ErrValidationFailed: %v", err)
+	}
+	assert(t, cfg.DB.Port, 70000)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("error (%v) was not joined", err)
+	}
+	errs := joined.Unwrap()
+	if len(errs) != 3 {
+		t.Fatalf("errors (%v) were not like expected", errs)
+	}
+
+	expected := []ValidationError{
+		{Key: "app:mode", Rule: "oneof=dev prod"},
+		{Key: "app:db:host", Rule: "required"},
+		{Key: "app:db:port", Rule: "max=65535"},
+	}
+	for i, exp := range expected {
+		var verr *ValidationError
+		if !errors.As(errs[i], &verr) || *verr != exp {
+			t.Errorf("error (%v) was not like expected (%+v)", errs[i], exp)
+		}
+		if !strings.Contains(err.Error(), exp.Key) {
+			t.Errorf("error message does not name key %s", exp.Key)
+		}
+	}
+
+	s.m["app"].(ConfigMap)["mode"] = "prod"
+	s.m["app"].(ConfigMap)["db"] = ConfigMap{"host": "localhost", "port": 5432}
+	if err = s.BindSchema("app", &cfg); err != nil {
+		t.Errorf("valid config returned error: %v", err)
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	if err := ValidateStruct(validateDB{Host: "a", Port: 1}); err != nil {
+		t.Errorf("valid struct returned error: %v", err)
+	}
+
+	err := ValidateStruct(&validateDB{Port: 0})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Key != "host" {
+		t.Errorf("error (%v) was not like expected", err)
+	}
+
+	if err := ValidateStruct(struct {
+		A int `validate:"between=1"`
+	}{}); err == nil || errors.Is(err, ErrValidationFailed) {
+		t.Errorf("unknown rule did not return an error: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package configoration
+
+import (
+	"math"
+	"reflect"
+	"sort"
+)
+
+// ValueType describes the type of a config
+// value returned by TypeOf.
+type ValueType int
+
+const (
+	// TypeOther is returned for values which
+	// do not match any other type, like
+	// time.Time values of TOML files.
+	TypeOther ValueType = iota
+
+	// TypeNull is returned for nil values.
+	TypeNull
+
+	// TypeString is returned for strings.
+	TypeString
+
+	// TypeInt is returned for signed and
+	// unsigned integers.
+	TypeInt
+
+	// TypeFloat is returned for floats. As JSON
+	// numbers are decoded as float64, they are
+	// always of this type.
+	TypeFloat
+
+	// TypeBool is returned for bools.
+	TypeBool
+
+	// TypeSection is returned for sections.
+	TypeSection
+
+	// TypeArray is returned for slices.
+	TypeArray
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeNull:
+		return "null"
+	case TypeString:
+		return "string"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeSection:
+		return "section"
+	case TypeArray:
+		return "array"
+	}
+	return "other"
+}
+
+func (s *section) TypeOf(key string) (ValueType, error) {
+	v, err := s.GetValue(key)
+	if err != nil {
+		return TypeOther, err
+	}
+
+	return typeOf(v), nil
+}
+
+// TypeMismatch describes a value whose type
+// differs from the expected one as returned by
+// CheckTypes.
+type TypeMismatch struct {
+	// Key is the key of the value.
+	Key string
+
+	// Expected is the expected type.
+	Expected ValueType
+
+	// Actual is the type of the stored value.
+	Actual ValueType
+
+	// Err is set if the value could not be
+	// read, like an ErrNil for missing keys.
+	Err error
+}
+
+func (s *section) CheckTypes(expected map[string]ValueType) []TypeMismatch {
+	var res []TypeMismatch
+	for key, et := range expected {
+		v, err := s.GetValue(key)
+		if err == nil && typeMatches(v, et) {
+			continue
+		}
+		vt := TypeOther
+		if err == nil {
+			vt = typeOf(v)
+		}
+		res = append(res, TypeMismatch{
+			Key:      key,
+			Expected: et,
+			Actual:   vt,
+			Err:      err,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// typeMatches returns true if v is of type t.
+// Like for StrictTypes, integral floats match
+// TypeInt, as JSON numbers are decoded as
+// float64, and ints match TypeFloat.
+func typeMatches(v interface{}, t ValueType) bool {
+	vt := typeOf(v)
+	switch {
+	case vt == t:
+		return true
+	case vt == TypeFloat && t == TypeInt:
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case vt == TypeInt && t == TypeFloat:
+		return true
+	}
+	return false
+}
+
+// typeOf returns the ValueType of v.
+func typeOf(v interface{}) ValueType {
+	if v == nil {
+		return TypeNull
+	}
+	if _, ok := toConfigMap(v); ok {
+		return TypeSection
+	}
+
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.Slice, reflect.Array:
+		return TypeArray
+	}
+	return TypeOther
+}
@@ -0,0 +1,129 @@
+package configoration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTypeOf(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"string":  "a",
+		"int":     1,
+		"uint":    uint8(1),
+		"float":   1.5,
+		"bool":    true,
+		"null":    nil,
+		"section": ConfigMap{"a": 1},
+		"map":     map[interface{}]interface{}{"a": 1},
+		"array":   []interface{}{1, 2},
+		"strings": []string{"a"},
+		"time":    time.Now(),
+	})
+
+	for key, expected := range map[string]ValueType{
+		"string":  TypeString,
+		"int":     TypeInt,
+		"uint":    TypeInt,
+		"float":   TypeFloat,
+		"bool":    TypeBool,
+		"null":    TypeNull,
+		"section": TypeSection,
+		"map":     TypeSection,
+		"array":   TypeArray,
+		"strings": TypeArray,
+		"time":    TypeOther,
+		"array:1": TypeInt,
+	} {
+		v, err := s.TypeOf(key)
+		assertVal(t, v, err, expected)
+	}
+
+	if _, err := s.TypeOf("missing"); !errors.Is(err, ErrNil) {
+		t.Errorf("missing key did not return ErrNil: %v", err)
+	}
+
+	assert(t, TypeSection.String(), "section")
+}
+
+func TestCheckTypes(t *testing.T) {
+	s := makeSection(ConfigMap{
+		"name":  "app",
+		"port":  "8080",
+		"debug": true,
+		"db": ConfigMap{
+			"host":    "localhost",
+			"timeout": 1.5,
+			"retries": 3,
+		},
+		"tags": []interface{}{"a", "b"},
+	})
+
+	res := s.CheckTypes(map[string]ValueType{
+		"name":       TypeString,
+		"port":       TypeInt,
+		"debug":      TypeBool,
+		"db":         TypeSection,
+		"db:host":    TypeString,
+		"db:timeout": TypeFloat,
+		"db:retries": TypeString,
+		"tags":       TypeArray,
+		"missing":    TypeString,
+	})
+
+	if len(res) != 3 {
+		t.Fatalf("mismatches (%+v) were not like expected", res)
+	}
+
+	expected := []TypeMismatch{
+		{Key: "db:retries", Expected: TypeString, Actual: TypeInt},
+		{Key: "missing", Expected: TypeString, Actual: TypeOther},
+		{Key: "port", Expected: TypeInt, Actual: TypeString},
+	}
+	for i, m := range res {
+		assert(t, m.Key, expected[i].Key)
+		assert(t, m.Expected, expected[i].Expected)
+		assert(t, m.Actual, expected[i].Actual)
+	}
+
+	if res[0].Err != nil || res[2].Err != nil {
+		t.Errorf("mismatches (%+v) had unexpected errors", res)
+	}
+	if !errors.Is(res[1].Err, ErrNil) {
+		t.Errorf("missing key error (%v) did not match ErrNil", res[1].Err)
+	}
+
+	if res := s.CheckTypes(map[string]ValueType{"name": TypeString}); res != nil {
+		t.Errorf("mismatches (%+v) were not nil", res)
+	}
+}
+
+func TestCheckTypesJson(t *testing.T) {
+	c, err := NewBuilder().
+		SetBasePath("testdata").
+		AddJsonFile("test1.json", false).
+		AddJsonFile("test2.json", false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+
+	res := c.CheckTypes(map[string]ValueType{
+		"a":       TypeString,
+		"b:b":     TypeInt,
+		"b:e":     TypeFloat,
+		"b:c":     TypeInt,
+		"g:e:f":   TypeBool,
+		"g:e":     TypeInt,
+		"a:type":  TypeString,
+		"g":       TypeSection,
+		"nothing": TypeNull,
+	})
+
+	keys := make([]string, len(res))
+	for i, m := range res {
+		keys[i] = m.Key
+	}
+	assertSlice(t, keys, nil, []string{"a:type", "g:e", "nothing"})
+	assert(t, res[1].Actual, TypeSection)
+}
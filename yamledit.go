@@ -0,0 +1,152 @@
+package configoration
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// EditYamlFile sets the passed values in the
+// YAML file fileName while preserving comments,
+// the order of keys and the indentation of the
+// file. The keys of values are split by the
+// Delimiter; missing sections are created and
+// elements of sequences can be selected by
+// their index.
+//
+// Comments attached to a replaced value are
+// kept for the new value.
+func EditYamlFile(fileName string, values map[string]interface{}) error {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	var doc yamlv3.Node
+	if err = yamlv3.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %s", fileName, err.Error())
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yamlv3.DocumentNode
+		doc.Content = []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err = setYamlNode(doc.Content[0], key, splitSections(key), values[key]); err != nil {
+			return fmt.Errorf("%s: %s", fileName, err.Error())
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(yamlIndent(data))
+	if err = enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err = enc.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, buf.Bytes(), info.Mode().Perm())
+}
+
+// setYamlNode sets v at the passed path
+// segments below node.
+func setYamlNode(node *yamlv3.Node, key string, segments []string, v interface{}) error {
+	for _, seg := range segments {
+		next, err := yamlChild(node, seg)
+		if err != nil {
+			return newKeyError(key, err)
+		}
+		node = next
+	}
+
+	var vn yamlv3.Node
+	if err := vn.Encode(v); err != nil {
+		return newKeyError(key, err)
+	}
+	vn.HeadComment = node.HeadComment
+	vn.LineComment = node.LineComment
+	vn.FootComment = node.FootComment
+	*node = vn
+
+	return nil
+}
+
+// yamlChild returns the value node of seg in the
+// mapping or sequence node. Missing keys are
+// added to mappings and empty nodes are turned
+// into mappings.
+func yamlChild(node *yamlv3.Node, seg string) (*yamlv3.Node, error) {
+	switch node.Kind {
+	case yamlv3.SequenceNode:
+		i, err := strconv.Atoi(seg)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil, ErrNil
+		}
+		return node.Content[i], nil
+	case yamlv3.ScalarNode:
+		if node.Tag != "!!null" {
+			return nil, ErrNil
+		}
+		*node = yamlv3.Node{
+			Kind:        yamlv3.MappingNode,
+			Tag:         "!!map",
+			HeadComment: node.HeadComment,
+			LineComment: node.LineComment,
+			FootComment: node.FootComment,
+		}
+	case yamlv3.MappingNode:
+	default:
+		return nil, ErrNil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == seg {
+			return node.Content[i+1], nil
+		}
+	}
+
+	kn := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: seg}
+	vn := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null"}
+	node.Content = append(node.Content, kn, vn)
+	return vn, nil
+}
+
+// yamlIndent returns the smallest indentation
+// of the lines of data or 2, if no line is
+// indented.
+func yamlIndent(data []byte) int {
+	indent := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '-' {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent == 0 {
+		return 2
+	}
+	return indent
+}
@@ -0,0 +1,77 @@
+package configoration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditYamlFile(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/edit.yaml")
+	if err != nil {
+		t.Fatalf("reading file failed: %s", err.Error())
+	}
+	fileName := filepath.Join(t.TempDir(), "edit.yaml")
+	if err = ioutil.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatalf("writing file failed: %s", err.Error())
+	}
+
+	err = EditYamlFile(fileName, map[string]interface{}{
+		"server:port":        9090,
+		"server:tls:enabled": true,
+		"tags:1":             "c",
+	})
+	if err != nil {
+		t.Fatalf("edit failed: %s", err.Error())
+	}
+
+	res, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("reading file failed: %s", err.Error())
+	}
+	for _, comment := range []string{
+		"# service configuration",
+		"# the service name",
+		"# listen address",
+		"port: 9090 # default port",
+	} {
+		if !strings.Contains(string(res), comment) {
+			t.Errorf("saved file does not contain %q", comment)
+		}
+	}
+	if strings.Index(string(res), "name:") > strings.Index(string(res), "server:") {
+		t.Error("order of keys was not preserved")
+	}
+
+	c, err := NewBuilder().
+		AddYamlFile(fileName, false).
+		Build()
+	if err != nil {
+		t.Fatalf("build failed: %s", err.Error())
+	}
+	{
+		v, err := c.GetInt("server:port")
+		assertVal(t, v, err, 9090)
+	}
+	{
+		v, err := c.GetString("server:host")
+		assertVal(t, v, err, "localhost")
+	}
+	{
+		v, err := c.GetBool("server:tls:enabled")
+		assertVal(t, v, err, true)
+	}
+	{
+		v, err := c.GetStringSlice("tags")
+		assertSlice(t, v, err, []string{"a", "c"})
+	}
+
+	if err = EditYamlFile(fileName, map[string]interface{}{"name:a": 1}); err == nil {
+		t.Error("editing a key below a value did not return an error")
+	}
+	if err = EditYamlFile(filepath.Join(t.TempDir(), "missing.yaml"), nil); !os.IsNotExist(err) {
+		t.Errorf("missing file did not return a not exist error: %v", err)
+	}
+}